@@ -0,0 +1,155 @@
+package acceptance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sclevine/spec"
+
+	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/layout"
+	"github.com/buildpacks/imgutil/layout/sparse"
+	"github.com/buildpacks/imgutil/local"
+	"github.com/buildpacks/imgutil/remote"
+	h "github.com/buildpacks/imgutil/testhelpers"
+)
+
+func testCopy(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir    string
+		layerPath string
+		src       imgutil.Image
+	)
+
+	it.Before(func() {
+		tmpDir = t.TempDir()
+
+		var err error
+		layerPath, err = h.CreateSingleFileLayerTar(fmt.Sprintf("/new-layer-%s.txt", h.RandString(10)), "new-layer-"+h.RandString(10), "linux")
+		h.AssertNil(t, err)
+
+		src, err = layout.NewImage(filepath.Join(tmpDir, "src"), layout.WithHistory())
+		h.AssertNil(t, err)
+		h.AssertNil(t, src.AddLayerWithDiffIDAndHistory(layerPath, diffIDOf(t, layerPath), v1.History{CreatedBy: "some-command"}))
+		h.AssertNil(t, src.SetLabel("some-label", "some-value"))
+		h.AssertNil(t, src.SetAnnotation("some-annotation", "some-value"))
+		h.AssertNil(t, src.Save())
+	})
+
+	it.After(func() {
+		h.AssertNil(t, os.Remove(layerPath))
+	})
+
+	assertSameContent := func(copied imgutil.Image) {
+		t.Helper()
+		label, err := copied.Label("some-label")
+		h.AssertNil(t, err)
+		h.AssertEq(t, label, "some-value")
+		history, err := copied.History()
+		h.AssertNil(t, err)
+		h.AssertEq(t, history[len(history)-1].CreatedBy, "some-command")
+		topLayer, err := copied.TopLayer()
+		h.AssertNil(t, err)
+		h.AssertEq(t, topLayer, diffIDOf(t, layerPath))
+	}
+
+	it("copies from a layout to a registry and back, preserving the digest", func() {
+		repoName := newTestImageName()
+		dst, err := remote.NewImage(repoName, authn.DefaultKeychain)
+		h.AssertNil(t, err)
+
+		result, err := imgutil.Copy(src, dst)
+		h.AssertNil(t, err)
+		h.AssertEq(t, result.DigestPreserved, true)
+		h.AssertEq(t, result.Digest, digestOf(t, src))
+		h.AssertEq(t, result.Reason, "")
+
+		pulled, err := remote.NewImage(repoName, authn.DefaultKeychain, remote.FromBaseImage(repoName))
+		h.AssertNil(t, err)
+		assertSameContent(pulled)
+		annotations, err := pulled.Annotations()
+		h.AssertNil(t, err)
+		h.AssertEq(t, annotations["some-annotation"], "some-value")
+
+		back, err := layout.NewImage(filepath.Join(tmpDir, "back"))
+		h.AssertNil(t, err)
+		result, err = imgutil.Copy(pulled, back)
+		h.AssertNil(t, err)
+		h.AssertEq(t, result.DigestPreserved, true)
+
+		reread, err := layout.NewImage(filepath.Join(tmpDir, "back"), layout.FromBaseImagePath(filepath.Join(tmpDir, "back")))
+		h.AssertNil(t, err)
+		assertSameContent(reread)
+		h.AssertEq(t, digestOf(t, reread), digestOf(t, src))
+	})
+
+	it("copies to a sparse layout without the layers", func() {
+		dst, err := sparse.NewImage(filepath.Join(tmpDir, "sparse"), src.UnderlyingImage())
+		h.AssertNil(t, err)
+
+		result, err := imgutil.Copy(src, dst)
+		h.AssertNil(t, err)
+		h.AssertEq(t, result.DigestPreserved, true)
+		h.AssertBlobsLen(t, filepath.Join(tmpDir, "sparse"), 2)
+	})
+
+	it("copies to and from a docker daemon, reporting that the digest is not preserved", func() {
+		dockerClient := h.DockerCli(t)
+		repoName := newTestImageName()
+		defer h.DockerRmi(dockerClient, repoName)
+
+		dst, err := local.NewImage(repoName, dockerClient)
+		h.AssertNil(t, err)
+		result, err := imgutil.Copy(src, dst)
+		h.AssertNil(t, err)
+		h.AssertEq(t, result.DigestPreserved, false)
+		h.AssertEq(t, result.Reason, "local images do not store the manifest")
+		configName, err := src.UnderlyingImage().ConfigName()
+		h.AssertNil(t, err)
+		h.AssertEq(t, result.ImageID, configName.String())
+
+		loaded, err := local.NewImage(repoName, dockerClient, local.FromBaseImage(repoName))
+		h.AssertNil(t, err)
+		assertSameContent(loaded)
+
+		back, err := layout.NewImage(filepath.Join(tmpDir, "from-daemon"))
+		h.AssertNil(t, err)
+		result, err = imgutil.Copy(loaded, back)
+		h.AssertNil(t, err)
+		h.AssertEq(t, result.DigestPreserved, false)
+		h.AssertEq(t, result.Reason, "local images have no manifest digest to preserve")
+		assertSameContent(back)
+	})
+
+	it("returns an error when the destination does not support copies", func() {
+		_, err := imgutil.Copy(src, &unsupportedImage{Image: src})
+		h.AssertTrue(t, func() bool { return errors.Is(err, imgutil.ErrNotImplemented) })
+	})
+}
+
+// unsupportedImage hides the CopyTarget implementation of the image it wraps.
+type unsupportedImage struct {
+	imgutil.Image
+}
+
+func diffIDOf(t *testing.T, layerPath string) string {
+	t.Helper()
+	f, err := os.Open(layerPath)
+	h.AssertNil(t, err)
+	defer f.Close()
+	digest, _, err := v1.SHA256(f)
+	h.AssertNil(t, err)
+	return digest.String()
+}
+
+func digestOf(t *testing.T, image imgutil.Image) v1.Hash {
+	t.Helper()
+	digest, err := image.UnderlyingImage().Digest()
+	h.AssertNil(t, err)
+	return digest
+}
@@ -41,6 +41,7 @@ func TestAcceptance(t *testing.T) {
 	defer os.Unsetenv("DOCKER_CONFIG")
 
 	spec.Run(t, "Reproducibility", testReproducibility, spec.Sequential(), spec.Report(report.Terminal{}))
+	spec.Run(t, "Copy", testCopy, spec.Sequential(), spec.Report(report.Terminal{}))
 }
 
 func testReproducibility(t *testing.T, when spec.G, it spec.S) {
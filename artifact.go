@@ -0,0 +1,172 @@
+package imgutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// Artifact is content attached to an image, such as an SBOM, a signature or a provenance attestation.
+// It is saved as an OCI image manifest whose subject is the image it is attached to.
+type Artifact struct {
+	// ArtifactType is the media type describing the artifact, e.g. "application/spdx+json".
+	ArtifactType string
+	// Blobs are the contents of the artifact.
+	Blobs []ArtifactBlob
+	// Annotations are set on the artifact manifest.
+	Annotations map[string]string
+}
+
+// ArtifactBlob is a piece of content of an artifact.
+type ArtifactBlob struct {
+	MediaType   types.MediaType
+	Data        []byte
+	Annotations map[string]string
+}
+
+// emptyJSON is the content of the config of artifacts, which hold no configuration.
+var emptyJSON = []byte("{}")
+
+const emptyJSONMediaType types.MediaType = "application/vnd.oci.empty.v1+json"
+
+// artifactManifest is an OCI image manifest with the artifactType field, which v1.Manifest lacks.
+type artifactManifest struct {
+	SchemaVersion int64             `json:"schemaVersion"`
+	MediaType     types.MediaType   `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        v1.Descriptor     `json:"config"`
+	Layers        []v1.Descriptor   `json:"layers"`
+	Subject       *v1.Descriptor    `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// ArtifactManifest returns the raw manifest of the artifact attached to subject, and each blob that the manifest
+// refers to, including its config.
+// The artifact type is set both as the artifactType of the manifest and as the media type of its config,
+// so that registries and clients that predate the artifactType field report it as well.
+func ArtifactManifest(artifact Artifact, subject v1.Descriptor) ([]byte, []v1.Layer, error) {
+	if artifact.ArtifactType == "" {
+		return nil, nil, errors.New("missing artifact type")
+	}
+	config, configBlob, err := artifactBlob(ArtifactBlob{MediaType: types.MediaType(artifact.ArtifactType), Data: emptyJSON})
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest := artifactManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		ArtifactType:  artifact.ArtifactType,
+		Config:        config,
+		Layers:        []v1.Descriptor{},
+		Subject:       &v1.Descriptor{MediaType: subject.MediaType, Size: subject.Size, Digest: subject.Digest},
+		Annotations:   artifact.Annotations,
+	}
+	contents := artifact.Blobs
+	if len(contents) == 0 {
+		// for portability, an artifact without content has an empty blob
+		contents = []ArtifactBlob{{MediaType: emptyJSONMediaType, Data: emptyJSON}}
+	}
+	blobs := []v1.Layer{configBlob}
+	for _, content := range contents {
+		desc, blob, err := artifactBlob(content)
+		if err != nil {
+			return nil, nil, err
+		}
+		manifest.Layers = append(manifest.Layers, desc)
+		blobs = append(blobs, blob)
+	}
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	return raw, blobs, nil
+}
+
+func artifactBlob(content ArtifactBlob) (v1.Descriptor, v1.Layer, error) {
+	blob := static.NewLayer(content.Data, content.MediaType)
+	digest, err := blob.Digest()
+	if err != nil {
+		return v1.Descriptor{}, nil, err
+	}
+	return v1.Descriptor{
+		MediaType:   content.MediaType,
+		Size:        int64(len(content.Data)),
+		Digest:      digest,
+		Annotations: content.Annotations,
+	}, blob, nil
+}
+
+// ReferrerDescriptor returns the descriptor that lists the artifact with the given raw manifest among the referrers
+// of its subject.
+func ReferrerDescriptor(raw []byte) (v1.Descriptor, error) {
+	var manifest artifactManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return v1.Descriptor{}, err
+	}
+	digest, size, err := v1.SHA256(bytes.NewReader(raw))
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	artifactType := manifest.ArtifactType
+	if artifactType == "" {
+		artifactType = string(manifest.Config.MediaType)
+	}
+	return v1.Descriptor{
+		MediaType:    manifest.MediaType,
+		Size:         size,
+		Digest:       digest,
+		Annotations:  manifest.Annotations,
+		ArtifactType: artifactType,
+	}, nil
+}
+
+// Subject returns the descriptor of the subject of the given raw manifest, or nil if it has none.
+func Subject(raw []byte) (*v1.Descriptor, error) {
+	var manifest artifactManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, err
+	}
+	return manifest.Subject, nil
+}
+
+// ParseArtifact returns the artifact with the given raw manifest, reading the contents of its blobs with readBlob.
+func ParseArtifact(raw []byte, readBlob func(desc v1.Descriptor) ([]byte, error)) (Artifact, error) {
+	var manifest artifactManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return Artifact{}, err
+	}
+	artifact := Artifact{ArtifactType: manifest.ArtifactType, Annotations: manifest.Annotations}
+	if artifact.ArtifactType == "" {
+		artifact.ArtifactType = string(manifest.Config.MediaType)
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == emptyJSONMediaType && len(manifest.Layers) == 1 {
+			// the empty blob of an artifact without content
+			break
+		}
+		data, err := readBlob(layer)
+		if err != nil {
+			return Artifact{}, err
+		}
+		artifact.Blobs = append(artifact.Blobs, ArtifactBlob{MediaType: layer.MediaType, Data: data, Annotations: layer.Annotations})
+	}
+	return artifact, nil
+}
+
+// FilterReferrers returns the referrers with the given artifact type, or all of them if artifactType is empty.
+func FilterReferrers(referrers []v1.Descriptor, artifactType string) []v1.Descriptor {
+	if artifactType == "" {
+		return referrers
+	}
+	var filtered []v1.Descriptor
+	for _, referrer := range referrers {
+		if referrer.ArtifactType == artifactType {
+			filtered = append(filtered, referrer)
+		}
+	}
+	return filtered
+}
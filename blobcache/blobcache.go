@@ -0,0 +1,252 @@
+// Package blobcache provides a content-addressed cache of compressed blobs on disk, so that the layers of images
+// read from a registry are only downloaded once per machine.
+//
+// The cache is safe for concurrent use by multiple processes sharing the same directory: blobs are written to
+// temporary files and renamed into place once their digest is verified, and a blob removed by the eviction of another
+// process is simply downloaded again.
+package blobcache
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	blobsDir = "blobs"
+	tmpDir   = "tmp"
+
+	// staleTempAge is the age after which a temporary file is assumed to be left over by a process that died.
+	staleTempAge = time.Hour
+)
+
+// Cache is a directory-backed cache of blobs, keyed by digest, that evicts the least recently used blobs
+// when it grows past its maximum size.
+type Cache struct {
+	dir     string
+	maxSize int64
+}
+
+// New returns a cache of the blobs in dir, creating the directory if needed.
+func New(dir string, ops ...Option) (*Cache, error) {
+	options := &options{}
+	for _, op := range ops {
+		op(options)
+	}
+	for _, d := range []string{filepath.Join(dir, blobsDir), filepath.Join(dir, tmpDir)} {
+		if err := os.MkdirAll(d, 0750); err != nil {
+			return nil, fmt.Errorf("creating blob cache directory %q: %w", d, err)
+		}
+	}
+	return &Cache{dir: dir, maxSize: options.maxSize}, nil
+}
+
+// Dir returns the directory of the cache.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get returns a reader of the blob with the given digest.
+// If the blob is not in the cache, the returned error matches fs.ErrNotExist.
+func (c *Cache) Get(digest v1.Hash) (io.ReadCloser, error) {
+	path, err := c.path(digest)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	// the modification time records the last use of the blob, for eviction
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return f, nil
+}
+
+// Contains tells whether the blob with the given digest is in the cache.
+func (c *Cache) Contains(digest v1.Hash) bool {
+	path, err := c.path(digest)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// Put stores the contents of r as the blob with the given digest, unless the blob is already in the cache.
+// The contents are only stored if they match the digest. The least recently used blobs are then evicted
+// if the cache is larger than its maximum size.
+func (c *Cache) Put(digest v1.Hash, r io.Reader) error {
+	if c.Contains(digest) {
+		return nil
+	}
+	w, err := c.newBlobWriter(digest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.abort()
+		return fmt.Errorf("writing blob %s: %w", digest, err)
+	}
+	return w.commit()
+}
+
+// blobWriter writes a blob to a temporary file, and stores it in the cache once it is complete.
+type blobWriter struct {
+	cache  *Cache
+	digest v1.Hash
+	path   string
+	tmp    *os.File
+	hasher hash.Hash
+}
+
+func (c *Cache) newBlobWriter(digest v1.Hash) (*blobWriter, error) {
+	path, err := c.path(digest)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Join(c.dir, tmpDir), digest.Hex+".*")
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary file for blob %s: %w", digest, err)
+	}
+	return &blobWriter{cache: c, digest: digest, path: path, tmp: tmp, hasher: sha256.New()}, nil
+}
+
+func (w *blobWriter) Write(p []byte) (int, error) {
+	w.hasher.Write(p)
+	return w.tmp.Write(p)
+}
+
+// commit stores the blob written so far, if it matches its digest, and evicts the least recently used blobs
+// if the cache is then larger than its maximum size.
+func (w *blobWriter) commit() error {
+	defer os.Remove(w.tmp.Name())
+	if err := w.tmp.Close(); err != nil {
+		return fmt.Errorf("writing blob %s: %w", w.digest, err)
+	}
+	if actual := fmt.Sprintf("%x", w.hasher.Sum(nil)); actual != w.digest.Hex {
+		return fmt.Errorf("blob has digest sha256:%s, expected %s", actual, w.digest)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(w.path), 0750); err != nil {
+		return err
+	}
+	if err := os.Rename(w.tmp.Name(), w.path); err != nil {
+		return fmt.Errorf("storing blob %s: %w", w.digest, err)
+	}
+	return w.cache.evict()
+}
+
+// abort discards the blob written so far.
+func (w *blobWriter) abort() {
+	w.tmp.Close()
+	os.Remove(w.tmp.Name())
+}
+
+// Size returns the total size in bytes of the blobs in the cache.
+func (c *Cache) Size() (int64, error) {
+	blobs, err := c.blobs()
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, blob := range blobs {
+		size += blob.size
+	}
+	return size, nil
+}
+
+func (c *Cache) path(digest v1.Hash) (string, error) {
+	if digest.Algorithm != "sha256" {
+		return "", fmt.Errorf("unsupported digest algorithm %q", digest.Algorithm)
+	}
+	return filepath.Join(c.dir, blobsDir, digest.Algorithm, digest.Hex), nil
+}
+
+type blob struct {
+	path    string
+	size    int64
+	lastUse time.Time
+}
+
+func (c *Cache) blobs() ([]blob, error) {
+	var blobs []blob
+	err := filepath.WalkDir(filepath.Join(c.dir, blobsDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// removed by another process
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		blobs = append(blobs, blob{path: path, size: info.Size(), lastUse: info.ModTime()})
+		return nil
+	})
+	return blobs, err
+}
+
+// evict removes the least recently used blobs until the cache is no larger than its maximum size,
+// as well as temporary files left over by processes that died while writing a blob.
+func (c *Cache) evict() error {
+	c.removeStaleTempFiles()
+	if c.maxSize <= 0 {
+		return nil
+	}
+
+	blobs, err := c.blobs()
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, blob := range blobs {
+		size += blob.size
+	}
+	sort.Slice(blobs, func(i, j int) bool {
+		return blobs[i].lastUse.Before(blobs[j].lastUse)
+	})
+	for _, blob := range blobs {
+		if size <= c.maxSize {
+			break
+		}
+		// a blob that another process has open is still readable by it, or, on Windows, cannot be removed
+		if err := os.Remove(blob.path); err == nil || errors.Is(err, fs.ErrNotExist) {
+			size -= blob.size
+		}
+	}
+	return nil
+}
+
+func (c *Cache) removeStaleTempFiles() {
+	entries, err := os.ReadDir(filepath.Join(c.dir, tmpDir))
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) > staleTempAge {
+			_ = os.Remove(filepath.Join(c.dir, tmpDir, entry.Name()))
+		}
+	}
+}
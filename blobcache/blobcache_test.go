@@ -0,0 +1,171 @@
+package blobcache_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/imgutil/blobcache"
+	h "github.com/buildpacks/imgutil/testhelpers"
+)
+
+func TestBlobCache(t *testing.T) {
+	spec.Run(t, "BlobCache", testBlobCache, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testBlobCache(t *testing.T, when spec.G, it spec.S) {
+	var cache *blobcache.Cache
+
+	digestOf := func(contents string) v1.Hash {
+		digest, _, err := v1.SHA256(strings.NewReader(contents))
+		h.AssertNil(t, err)
+		return digest
+	}
+
+	put := func(contents string) v1.Hash {
+		digest := digestOf(contents)
+		h.AssertNil(t, cache.Put(digest, strings.NewReader(contents)))
+		return digest
+	}
+
+	read := func(rc io.ReadCloser, err error) string {
+		h.AssertNil(t, err)
+		defer rc.Close()
+		contents, err := io.ReadAll(rc)
+		h.AssertNil(t, err)
+		return string(contents)
+	}
+
+	it.Before(func() {
+		var err error
+		cache, err = blobcache.New(t.TempDir())
+		h.AssertNil(t, err)
+	})
+
+	when("#Get", func() {
+		it("returns the blob stored with #Put", func() {
+			digest := put("some-blob")
+
+			h.AssertEq(t, read(cache.Get(digest)), "some-blob")
+			size, err := cache.Size()
+			h.AssertNil(t, err)
+			h.AssertEq(t, size, int64(len("some-blob")))
+		})
+
+		it("returns a not exist error when the blob is not in the cache", func() {
+			_, err := cache.Get(digestOf("some-blob"))
+			h.AssertEq(t, errors.Is(err, fs.ErrNotExist), true)
+		})
+	})
+
+	when("#Put", func() {
+		it("does not store contents that don't match the digest", func() {
+			digest := digestOf("some-blob")
+
+			err := cache.Put(digest, strings.NewReader("some-other-blob"))
+			h.AssertError(t, err, "expected "+digest.String())
+			_, err = cache.Get(digest)
+			h.AssertEq(t, errors.Is(err, fs.ErrNotExist), true)
+		})
+
+		it("evicts the least recently used blobs when the cache is larger than its maximum size", func() {
+			var err error
+			cache, err = blobcache.New(t.TempDir(), blobcache.WithMaxSize(25))
+			h.AssertNil(t, err)
+
+			first := put("first-blob")
+			time.Sleep(10 * time.Millisecond)
+			second := put("second-blob")
+			time.Sleep(10 * time.Millisecond)
+			h.AssertEq(t, read(cache.Get(first)), "first-blob")
+			time.Sleep(10 * time.Millisecond)
+			third := put("third-blob")
+
+			h.AssertEq(t, read(cache.Get(first)), "first-blob")
+			h.AssertEq(t, read(cache.Get(third)), "third-blob")
+			_, err = cache.Get(second)
+			h.AssertEq(t, errors.Is(err, fs.ErrNotExist), true)
+		})
+	})
+
+	when("#Layer", func() {
+		it("reads the layer through the cache", func() {
+			layer, err := random.Layer(1024, types.OCILayer)
+			h.AssertNil(t, err)
+			expected := read(layer.Uncompressed())
+
+			cached, err := cache.Layer(layer)
+			h.AssertNil(t, err)
+			h.AssertEq(t, read(cached.Uncompressed()), expected)
+
+			// once cached, the layer is not read from its source again
+			cached, err = cache.Layer(&unreadableLayer{Layer: layer})
+			h.AssertNil(t, err)
+			h.AssertEq(t, read(cached.Uncompressed()), expected)
+			digest, err := layer.Digest()
+			h.AssertNil(t, err)
+			compressed, err := layer.Compressed()
+			h.AssertNil(t, err)
+			h.AssertEq(t, read(cache.Get(digest)), read(compressed, nil))
+		})
+
+		it("reads the layer from its source when it cannot be added to the cache", func() {
+			var err error
+			dir := t.TempDir()
+			cache, err = blobcache.New(dir)
+			h.AssertNil(t, err)
+			h.AssertNil(t, os.RemoveAll(filepath.Join(dir, "tmp")))
+
+			layer, err := random.Layer(1024, types.OCILayer)
+			h.AssertNil(t, err)
+			expected := read(layer.Uncompressed())
+
+			cached, err := cache.Layer(layer)
+			h.AssertNil(t, err)
+			h.AssertEq(t, read(cached.Uncompressed()), expected)
+			digest, err := layer.Digest()
+			h.AssertNil(t, err)
+			h.AssertEq(t, cache.Contains(digest), false)
+		})
+
+		it("does not add layers larger than the cache", func() {
+			var err error
+			cache, err = blobcache.New(t.TempDir(), blobcache.WithMaxSize(10))
+			h.AssertNil(t, err)
+
+			layer, err := random.Layer(1024, types.OCILayer)
+			h.AssertNil(t, err)
+			expected := read(layer.Uncompressed())
+
+			cached, err := cache.Layer(layer)
+			h.AssertNil(t, err)
+			h.AssertEq(t, read(cached.Uncompressed()), expected)
+			size, err := cache.Size()
+			h.AssertNil(t, err)
+			h.AssertEq(t, size, int64(0))
+		})
+	})
+}
+
+type unreadableLayer struct {
+	v1.Layer
+}
+
+func (l *unreadableLayer) Compressed() (io.ReadCloser, error) {
+	return nil, errors.New("unreadable")
+}
+
+func (l *unreadableLayer) Uncompressed() (io.ReadCloser, error) {
+	return nil, errors.New("unreadable")
+}
@@ -0,0 +1,140 @@
+package blobcache
+
+import (
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// Layer returns a layer with the same contents as layer, whose compressed contents are read from the cache,
+// and are added to the cache from layer when they are missing.
+// Layers of images read from a registry remain mountable from the repository they were read from.
+func (c *Cache) Layer(layer v1.Layer) (v1.Layer, error) {
+	if mountable, ok := layer.(*remote.MountableLayer); ok {
+		inner, err := c.Layer(mountable.Layer)
+		if err != nil {
+			return nil, err
+		}
+		return &remote.MountableLayer{Layer: inner, Reference: mountable.Reference}, nil
+	}
+	return partial.CompressedToLayer(&cachedLayer{layer: layer, cache: c})
+}
+
+// Image returns an image with the same manifest and config as image, whose layers are read through the cache.
+func (c *Cache) Image(image v1.Image) v1.Image {
+	return &cachedImage{Image: image, cache: c}
+}
+
+type cachedLayer struct {
+	layer v1.Layer
+	cache *Cache
+}
+
+// Compressed reads the layer from the cache, or else from its source while adding it to the cache.
+// The cache is best-effort: when the layer cannot be added to it, or is larger than the cache, it is only streamed.
+func (l *cachedLayer) Compressed() (io.ReadCloser, error) {
+	digest, err := l.layer.Digest()
+	if err != nil {
+		return nil, err
+	}
+	if rc, err := l.cache.Get(digest); err == nil {
+		return rc, nil
+	}
+
+	rc, err := l.layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	if size, err := l.layer.Size(); err == nil && l.cache.maxSize > 0 && size > l.cache.maxSize {
+		return rc, nil
+	}
+	w, err := l.cache.newBlobWriter(digest)
+	if err != nil {
+		return rc, nil
+	}
+	return &cachingReader{rc: rc, w: w}, nil
+}
+
+func (l *cachedLayer) Digest() (v1.Hash, error) {
+	return l.layer.Digest()
+}
+
+func (l *cachedLayer) DiffID() (v1.Hash, error) {
+	return l.layer.DiffID()
+}
+
+func (l *cachedLayer) Size() (int64, error) {
+	return l.layer.Size()
+}
+
+func (l *cachedLayer) MediaType() (types.MediaType, error) {
+	return l.layer.MediaType()
+}
+
+type cachedImage struct {
+	v1.Image
+	cache *Cache
+}
+
+func (i *cachedImage) Layers() ([]v1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	cached := make([]v1.Layer, len(layers))
+	for idx, layer := range layers {
+		if cached[idx], err = i.cache.Layer(layer); err != nil {
+			return nil, err
+		}
+	}
+	return cached, nil
+}
+
+func (i *cachedImage) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	layer, err := i.Image.LayerByDigest(h)
+	if err != nil {
+		return nil, err
+	}
+	return i.cache.Layer(layer)
+}
+
+func (i *cachedImage) LayerByDiffID(h v1.Hash) (v1.Layer, error) {
+	layer, err := i.Image.LayerByDiffID(h)
+	if err != nil {
+		return nil, err
+	}
+	return i.cache.Layer(layer)
+}
+
+// cachingReader adds a blob to the cache as it is read.
+// The blob is only added once it has been read to the end, and failing to add it doesn't fail the read.
+type cachingReader struct {
+	rc io.ReadCloser
+	w  *blobWriter // nil once the blob is added, or could not be
+}
+
+func (r *cachingReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	if r.w != nil && n > 0 {
+		if _, werr := r.w.Write(p[:n]); werr != nil {
+			r.w.abort()
+			r.w = nil
+		}
+	}
+	if err == io.EOF && r.w != nil {
+		_ = r.w.commit()
+		r.w = nil
+	}
+	return n, err
+}
+
+func (r *cachingReader) Close() error {
+	if r.w != nil {
+		r.w.abort()
+		r.w = nil
+	}
+	return r.rc.Close()
+}
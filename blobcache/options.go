@@ -0,0 +1,16 @@
+package blobcache
+
+type Option func(*options)
+
+type options struct {
+	maxSize int64
+}
+
+// WithMaxSize bounds the total size in bytes of the blobs in the cache.
+// When a blob is added to a cache that grows past this size, the least recently used blobs are evicted.
+// If not provided, or if size is not positive, the cache is unbounded.
+func WithMaxSize(size int64) Option {
+	return func(o *options) {
+		o.maxSize = size
+	}
+}
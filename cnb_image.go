@@ -1,6 +1,7 @@
 package imgutil
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -38,6 +39,67 @@ type ImageStore interface {
 	Layers() []v1.Layer
 }
 
+// ContextImageStore is an ImageStore whose operations are aborted when the provided context is done.
+// SaveContext also returns the outcome of saving the image as each name.
+type ContextImageStore interface {
+	ImageStore
+	ContainsContext(ctx context.Context, identifier string) bool
+	DeleteContext(ctx context.Context, identifier string) error
+	SaveContext(ctx context.Context, image IdentifiableV1Image, withName string, withAdditionalNames ...string) ([]SaveResult, error)
+	SaveFileContext(ctx context.Context, image IdentifiableV1Image, withName string) (string, error)
+
+	DownloadLayersForContext(ctx context.Context, identifier string) error
+}
+
+// ContextStore returns the store as a ContextImageStore.
+// A store that doesn't implement ContextImageStore ignores the context,
+// and the identifier returned by its Save method is reported as the image ID for each name.
+func ContextStore(store ImageStore) ContextImageStore {
+	if contextStore, ok := store.(ContextImageStore); ok {
+		return contextStore
+	}
+	return contextIgnoringStore{ImageStore: store}
+}
+
+type contextIgnoringStore struct {
+	ImageStore
+}
+
+func (s contextIgnoringStore) ContainsContext(_ context.Context, identifier string) bool {
+	return s.Contains(identifier)
+}
+
+func (s contextIgnoringStore) DeleteContext(_ context.Context, identifier string) error {
+	return s.Delete(identifier)
+}
+
+func (s contextIgnoringStore) SaveContext(_ context.Context, image IdentifiableV1Image, withName string, withAdditionalNames ...string) ([]SaveResult, error) {
+	identifier, err := s.Save(image, withName, withAdditionalNames...)
+	var saveErr SaveError
+	if err != nil && !errors.As(err, &saveErr) {
+		return nil, err
+	}
+	var results []SaveResult
+	for _, n := range append([]string{withName}, withAdditionalNames...) {
+		result := SaveResult{Name: n, ImageID: identifier}
+		for _, d := range saveErr.Errors {
+			if d.ImageName == n {
+				result = SaveResult{Name: n, Err: d.Cause}
+			}
+		}
+		results = append(results, result)
+	}
+	return results, err
+}
+
+func (s contextIgnoringStore) SaveFileContext(_ context.Context, image IdentifiableV1Image, withName string) (string, error) {
+	return s.SaveFile(image, withName)
+}
+
+func (s contextIgnoringStore) DownloadLayersForContext(_ context.Context, identifier string) error {
+	return s.DownloadLayersFor(identifier)
+}
+
 type IdentifiableV1Image interface {
 	v1.Image
 	Identifier() (Identifier, error)
@@ -45,7 +107,17 @@ type IdentifiableV1Image interface {
 
 var _ v1.Image = &CNBImageCore{}
 
-// FIXME: mark deprecated methods as deprecated on the interface when other packages (remote, layout) expose a v1.Image
+func (i *CNBImageCore) Annotations() (map[string]string, error) {
+	manifest, err := getManifest(i.Image)
+	if err != nil {
+		return nil, err
+	}
+	annotations := make(map[string]string, len(manifest.Annotations))
+	for k, v := range manifest.Annotations {
+		annotations[k] = v
+	}
+	return annotations, nil
+}
 
 // Deprecated: Architecture
 func (i *CNBImageCore) Architecture() (string, error) {
@@ -56,6 +128,15 @@ func (i *CNBImageCore) Architecture() (string, error) {
 	return configFile.Architecture, nil
 }
 
+// ConfigFile returns a copy of the config file of the image.
+func (i *CNBImageCore) ConfigFile() (*v1.ConfigFile, error) {
+	configFile, err := getConfigFile(i.Image)
+	if err != nil {
+		return nil, err
+	}
+	return configFile.DeepCopy(), nil
+}
+
 // Deprecated: CreatedAt
 func (i *CNBImageCore) CreatedAt() (time.Time, error) {
 	configFile, err := getConfigFile(i.Image)
@@ -204,12 +285,15 @@ func (i *CNBImageCore) WorkingDir() (string, error) {
 }
 
 func (i *CNBImageCore) AnnotateRefName(refName string) error {
-	manifest, err := getManifest(i.Image)
-	if err != nil {
-		return err
-	}
-	manifest.Annotations["org.opencontainers.image.ref.name"] = refName
-	mutated := mutate.Annotations(i.Image, manifest.Annotations)
+	return i.SetAnnotation("org.opencontainers.image.ref.name", refName)
+}
+
+func (i *CNBImageCore) Rename(name string) {
+	i.repoName = name
+}
+
+func (i *CNBImageCore) SetAnnotation(key, value string) error {
+	mutated := mutate.Annotations(i.Image, map[string]string{key: value})
 	image, ok := mutated.(v1.Image)
 	if !ok {
 		return fmt.Errorf("failed to add annotation")
@@ -218,10 +302,6 @@ func (i *CNBImageCore) AnnotateRefName(refName string) error {
 	return nil
 }
 
-func (i *CNBImageCore) Rename(name string) {
-	i.repoName = name
-}
-
 // Deprecated: SetArchitecture
 func (i *CNBImageCore) SetArchitecture(architecture string) error {
 	return i.MutateConfigFile(func(c *v1.ConfigFile) {
@@ -346,15 +426,25 @@ func (i *CNBImageCore) AddLayerWithDiffIDAndHistory(path, _ string, history v1.H
 }
 
 func (i *CNBImageCore) Rebase(baseTopLayerDiffID string, withNewBase Image) error {
-	if i.Kind() != withNewBase.Kind() {
-		return fmt.Errorf("expected new base to be a %s image; got %s", i.Kind(), withNewBase.Kind())
-	}
-	newBase := withNewBase.UnderlyingImage() // FIXME: when all imgutil.Images are v1.Images, we can remove this part
+	// a new base of the same kind that is a v1.Image shares its store with the image, so its layers are read as is;
+	// the store of a new base of another kind does not share layers with the store of the image
+	newBase, ok := withNewBase.(v1.Image)
 	var err error
+	if !ok || i.Kind() != withNewBase.Kind() {
+		if newBase, err = NewBaseImage(withNewBase); err != nil {
+			return err
+		}
+	}
+	annotations, err := i.Annotations()
+	if err != nil {
+		return err
+	}
 	i.Image, err = mutate.Rebase(i.Image, i.newV1ImageFacade(baseTopLayerDiffID), newBase)
 	if err != nil {
 		return err
 	}
+	// keep the manifest annotations of the original image
+	i.Image = OverrideAnnotations(i.Image, annotations)
 
 	// ensure new config matches provided image
 	newBaseConfigFile, err := getConfigFile(newBase)
@@ -369,8 +459,14 @@ func (i *CNBImageCore) Rebase(baseTopLayerDiffID string, withNewBase Image) erro
 }
 
 func (i *CNBImageCore) newV1ImageFacade(topLayerDiffID string) v1.Image {
+	return SubImage(i, topLayerDiffID)
+}
+
+// SubImage returns an image with the layers of image up to and including the layer with the given diff ID,
+// such as the old base of the image to provide to mutate.Rebase.
+func SubImage(image v1.Image, topLayerDiffID string) v1.Image {
 	return &v1ImageFacade{
-		Image:          i,
+		Image:          image,
 		topLayerDiffID: topLayerDiffID,
 	}
 }
@@ -397,6 +493,16 @@ func (si *v1ImageFacade) Layers() ([]v1.Layer, error) {
 	return nil, errors.New("could not find base layer in image")
 }
 
+func (i *CNBImageCore) RemoveAnnotation(key string) error {
+	annotations, err := i.Annotations()
+	if err != nil {
+		return err
+	}
+	delete(annotations, key)
+	i.Image = OverrideAnnotations(i.Image, annotations)
+	return nil
+}
+
 func (i *CNBImageCore) RemoveLabel(key string) error {
 	return i.MutateConfigFile(func(c *v1.ConfigFile) {
 		delete(c.Config.Labels, key)
@@ -432,7 +538,7 @@ func getLayerIndex(forDiffID string, fromImage v1.Image) (int, error) {
 			return idx, nil
 		}
 	}
-	return -1, fmt.Errorf("failed to find diffID %s in config file", layerHash.String())
+	return -1, Errorf(ErrLayerNotFound, "failed to find diffID %s in config file", layerHash.String())
 }
 
 func getHistory(forIndex int, fromImage v1.Image) (v1.History, error) {
@@ -472,12 +578,15 @@ func (i *CNBImageCore) ReuseLayerWithHistory(diffID string, history v1.History)
 // helpers
 
 func (i *CNBImageCore) MutateConfigFile(withFunc func(c *v1.ConfigFile)) error {
-	// FIXME: put MutateConfigFile on the interface when `remote` and `layout` packages also support it.
-	configFile, err := getConfigFile(i.Image)
+	before, err := getConfigFile(i.Image)
 	if err != nil {
 		return err
 	}
+	configFile := before.DeepCopy()
 	withFunc(configFile)
+	if err = ValidateConfigFileMutation(before, configFile); err != nil {
+		return err
+	}
 	i.Image, err = mutate.ConfigFile(i.Image, configFile)
 	return err
 }
@@ -0,0 +1,89 @@
+package imgutil
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// CopySource is implemented by images that do not keep their content as a v1.Image, such as images in a docker daemon,
+// to provide it for copying. Images that are not a CopySource are copied from their UnderlyingImage.
+type CopySource interface {
+	// CopyContent returns the content of the image as it was last saved,
+	// and a function that releases what the content is read from, e.g. a temporary file, once it has been copied.
+	CopyContent(ctx context.Context) (v1.Image, func(), error)
+}
+
+// CopyTarget is implemented by images that content of other images can be copied to.
+type CopyTarget interface {
+	// SaveCopy saves image as the name of the target as is, without the normalization done by Save,
+	// and makes it the content of the target.
+	SaveCopy(ctx context.Context, image v1.Image) (SaveResult, error)
+}
+
+// CopyResult describes the outcome of copying an image.
+type CopyResult struct {
+	SaveResult
+	// SourceDigest is the manifest digest of the image that was copied.
+	// It is empty for images copied from a docker daemon, which does not store the manifest.
+	SourceDigest v1.Hash
+	// DigestPreserved tells whether the copy has the manifest digest of the image that was copied.
+	DigestPreserved bool
+	// Reason explains why the manifest digest was not preserved, when it was not.
+	Reason string
+}
+
+// Copy saves the content of src, i.e. its config, layers, history and manifest annotations, as dst,
+// without rebuilding it, so that the copy keeps the manifest digest of src where the store of dst allows it.
+// dst is an image created for the name to copy to, e.g. with remote.NewImage; its own content and options are
+// ignored.
+// Copying to or from a docker daemon changes the manifest digest, as the daemon does not store the manifest,
+// and is reported in the result.
+func Copy(src, dst Image) (CopyResult, error) {
+	return CopyContext(context.Background(), src, dst)
+}
+
+// CopyContext is like Copy, but aborts the copy when ctx is done.
+func CopyContext(ctx context.Context, src, dst Image) (CopyResult, error) {
+	target, ok := dst.(CopyTarget)
+	if !ok {
+		return CopyResult{}, Errorf(ErrNotImplemented, "copying to %s images is not supported", dst.Kind())
+	}
+
+	var (
+		content v1.Image
+		result  CopyResult
+		err     error
+	)
+	if source, ok := src.(CopySource); ok {
+		var release func()
+		if content, release, err = source.CopyContent(ctx); err != nil {
+			return CopyResult{}, fmt.Errorf("reading image %q: %w", src.Name(), err)
+		}
+		defer release()
+	} else {
+		if content = src.UnderlyingImage(); content == nil {
+			return CopyResult{}, Errorf(ErrNotImplemented, "copying from %s images is not supported", src.Kind())
+		}
+		if result.SourceDigest, err = content.Digest(); err != nil {
+			return CopyResult{}, fmt.Errorf("getting digest of image %q: %w", src.Name(), err)
+		}
+	}
+
+	if result.SaveResult, err = target.SaveCopy(ctx, content); err != nil {
+		return CopyResult{}, fmt.Errorf("copying image %q to %q: %w", src.Name(), dst.Name(), err)
+	}
+
+	switch {
+	case result.SourceDigest == v1.Hash{}:
+		result.Reason = fmt.Sprintf("%s images have no manifest digest to preserve", src.Kind())
+	case result.Digest == v1.Hash{}:
+		result.Reason = fmt.Sprintf("%s images do not store the manifest", dst.Kind())
+	case result.Digest != result.SourceDigest:
+		result.Reason = fmt.Sprintf("%s images do not store the manifest as is", dst.Kind())
+	default:
+		result.DigestPreserved = true
+	}
+	return result, nil
+}
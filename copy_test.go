@@ -0,0 +1,91 @@
+package imgutil_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/fakes"
+	"github.com/buildpacks/imgutil/layout"
+	h "github.com/buildpacks/imgutil/testhelpers"
+)
+
+func TestCopy(t *testing.T) {
+	spec.Run(t, "Copy", testCopy, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testCopy(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir string
+		src    *layout.Image
+	)
+
+	it.Before(func() {
+		tmpDir = t.TempDir()
+
+		var err error
+		src, err = layout.NewImage(filepath.Join(tmpDir, "src"))
+		h.AssertNil(t, err)
+		layerPath, _, _ := h.RandomLayer(t, tmpDir)
+		h.AssertNil(t, src.AddLayer(layerPath))
+		h.AssertNil(t, src.SetLabel("some-key", "some-value"))
+		h.AssertNil(t, src.Save())
+	})
+
+	when("#Copy", func() {
+		it("copies the image as is and preserves its digest", func() {
+			dst, err := layout.NewImage(filepath.Join(tmpDir, "dst"))
+			h.AssertNil(t, err)
+
+			result, err := imgutil.Copy(src, dst)
+			h.AssertNil(t, err)
+
+			srcDigest, err := src.UnderlyingImage().Digest()
+			h.AssertNil(t, err)
+			h.AssertEq(t, result.SourceDigest, srcDigest)
+			h.AssertEq(t, result.Digest, srcDigest)
+			h.AssertEq(t, result.DigestPreserved, true)
+			h.AssertEq(t, result.Reason, "")
+
+			index := h.ReadIndexManifest(t, filepath.Join(tmpDir, "dst"))
+			h.AssertEq(t, len(index.Manifests), 1)
+			h.AssertEq(t, index.Manifests[0].Digest, srcDigest)
+			label, err := dst.Label("some-key")
+			h.AssertNil(t, err)
+			h.AssertEq(t, label, "some-value")
+		})
+
+		it("returns an error when the destination cannot be copied to", func() {
+			dst := fakes.NewImage("some-image", "", nil)
+
+			_, err := imgutil.Copy(src, dst)
+			h.AssertEq(t, errors.Is(err, imgutil.ErrNotImplemented), true)
+		})
+
+		it("returns an error when the source has no content to copy", func() {
+			dst, err := layout.NewImage(filepath.Join(tmpDir, "dst"))
+			h.AssertNil(t, err)
+
+			_, err = imgutil.Copy(fakes.NewImage("some-image", "", nil), dst)
+			h.AssertEq(t, errors.Is(err, imgutil.ErrNotImplemented), true)
+		})
+	})
+
+	when("#CopyContext", func() {
+		it("returns an error when the context is done", func() {
+			dst, err := layout.NewImage(filepath.Join(tmpDir, "dst"))
+			h.AssertNil(t, err)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err = imgutil.CopyContext(ctx, src, dst)
+			h.AssertEq(t, errors.Is(err, context.Canceled), true)
+			h.AssertEq(t, layout.ImageExists(filepath.Join(tmpDir, "dst")), false)
+		})
+	})
+}
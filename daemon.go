@@ -0,0 +1,59 @@
+package imgutil
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// CloseOnDone closes the provided pipe reader with the context error once ctx is done,
+// so that a producer blocked writing to the pipe is released.
+// The returned function must be called to stop watching ctx.
+func CloseOnDone(ctx context.Context, pr *io.PipeReader) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			pr.CloseWithError(ctx.Err())
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// ExportImage saves the image with the given identifier from a docker daemon to a temporary file with save,
+// e.g. the ImageSave method of a docker client, and reads the image from the file.
+// The returned function removes the file; it must be called once the image is no longer read.
+func ExportImage(ctx context.Context, save func(ctx context.Context, images []string) (io.ReadCloser, error), identifier string) (v1.Image, func(), error) {
+	imageReader, err := save(ctx, []string{identifier})
+	if err != nil {
+		return nil, nil, fmt.Errorf("saving image with ID %q from the docker daemon: %w", identifier, err)
+	}
+	defer imageReader.Close()
+
+	f, err := os.CreateTemp("", "imgutil.local.image.export.*.tar")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	cleanup := func() {
+		_ = os.Remove(f.Name())
+	}
+	_, err = io.Copy(f, imageReader)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("saving image with ID %q from the docker daemon: %w", identifier, err)
+	}
+	image, err := tarball.ImageFromPath(f.Name(), nil)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return image, cleanup, nil
+}
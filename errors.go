@@ -0,0 +1,55 @@
+package imgutil
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the Image and ImageIndex implementations wrap the following errors,
+// so that callers can check for them with errors.Is instead of matching error messages.
+var (
+	// ErrImageNotFound is returned when an image or a repository does not exist.
+	ErrImageNotFound = errors.New("image not found")
+	// ErrLayerNotFound is returned when a layer is not part of an image, or its contents are missing.
+	ErrLayerNotFound = errors.New("layer not found")
+	// ErrUnauthorized is returned when the credentials are missing or are not allowed to access an image.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrPlatformMismatch is returned when an image for the requested platform cannot be provided.
+	ErrPlatformMismatch = errors.New("platform mismatch")
+	// ErrNotImplemented is returned when an operation is not supported by an implementation.
+	ErrNotImplemented = errors.New("not implemented")
+	// ErrAnnotationsNotSupported is returned when manifest annotations are read or set on an image in a docker daemon,
+	// which does not store them. It is of the kind ErrNotImplemented.
+	ErrAnnotationsNotSupported = Errorf(ErrNotImplemented, "manifest annotations are not supported for images in a docker daemon")
+	// ErrRateLimited is returned when a registry rejected a request because too many requests were made.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrIncompatibleBase is returned when a new base image fails the validation requested for a rebase.
+	ErrIncompatibleBase = errors.New("incompatible base image")
+)
+
+// WrapError returns an error with the same message as err that matches both kind and err with errors.Is and errors.As.
+// If err is nil, WrapError returns nil.
+func WrapError(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// Errorf is like fmt.Errorf, but the returned error also matches kind with errors.Is.
+func Errorf(kind error, format string, args ...interface{}) error {
+	return WrapError(kind, fmt.Errorf(format, args...))
+}
+
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.err, e.kind}
+}
@@ -0,0 +1,65 @@
+package imgutil_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/imgutil"
+	h "github.com/buildpacks/imgutil/testhelpers"
+)
+
+func TestErrors(t *testing.T) {
+	spec.Run(t, "Errors", testErrors, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testErrors(t *testing.T, when spec.G, it spec.S) {
+	when("#Errorf", func() {
+		it("returns an error with the message that matches the kind", func() {
+			err := imgutil.Errorf(imgutil.ErrImageNotFound, "image %q not found", "some-image")
+			h.AssertEq(t, err.Error(), `image "some-image" not found`)
+			h.AssertEq(t, errors.Is(err, imgutil.ErrImageNotFound), true)
+			h.AssertEq(t, errors.Is(err, imgutil.ErrLayerNotFound), false)
+		})
+
+		it("matches the errors wrapped with %w", func() {
+			err := imgutil.Errorf(imgutil.ErrLayerNotFound, "reading layer: %w", fs.ErrNotExist)
+			h.AssertEq(t, errors.Is(err, imgutil.ErrLayerNotFound), true)
+			h.AssertEq(t, errors.Is(err, fs.ErrNotExist), true)
+		})
+
+		it("still matches the kind when wrapped again", func() {
+			err := fmt.Errorf("saving image: %w", imgutil.Errorf(imgutil.ErrUnauthorized, "some error"))
+			h.AssertEq(t, errors.Is(err, imgutil.ErrUnauthorized), true)
+		})
+	})
+
+	when("#WrapError", func() {
+		it("returns nil for a nil error", func() {
+			h.AssertNil(t, imgutil.WrapError(imgutil.ErrRateLimited, nil))
+		})
+
+		it("keeps the message and the type of the error", func() {
+			pathErr := &fs.PathError{Op: "open", Path: "some-path", Err: fs.ErrNotExist}
+
+			err := imgutil.WrapError(imgutil.ErrLayerNotFound, pathErr)
+			h.AssertEq(t, err.Error(), pathErr.Error())
+			h.AssertEq(t, errors.Is(err, imgutil.ErrLayerNotFound), true)
+			h.AssertEq(t, errors.Is(err, fs.ErrNotExist), true)
+
+			var asPathErr *fs.PathError
+			h.AssertEq(t, errors.As(err, &asPathErr), true)
+			h.AssertEq(t, asPathErr.Path, "some-path")
+		})
+	})
+
+	when("ErrAnnotationsNotSupported", func() {
+		it("is of the kind ErrNotImplemented", func() {
+			h.AssertEq(t, errors.Is(imgutil.ErrAnnotationsNotSupported, imgutil.ErrNotImplemented), true)
+		})
+	})
+}
@@ -2,12 +2,14 @@ package fakes
 
 import (
 	"archive/tar"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -33,6 +35,7 @@ func NewImage(name, topLayerSha string, identifier imgutil.Identifier) *Image {
 		os:               "linux",
 		osVersion:        "",
 		architecture:     "amd64",
+		annotations:      map[string]string{},
 		savedAnnotations: map[string]string{},
 	}
 }
@@ -62,7 +65,49 @@ type Image struct {
 	savedNames       map[string]bool
 	manifestSize     int64
 	refName          string
+	annotations      map[string]string
 	savedAnnotations map[string]string
+	config           v1.Config // fields of the container config that the fake has no dedicated field for
+}
+
+func (i *Image) Annotations() (map[string]string, error) {
+	annotations := make(map[string]string)
+	for k, v := range i.annotations {
+		annotations[k] = v
+	}
+	if i.refName != "" {
+		annotations["org.opencontainers.image.ref.name"] = i.refName
+	}
+	return annotations, nil
+}
+
+// ConfigFile returns a config file built from the fields of the fake.
+func (i *Image) ConfigFile() (*v1.ConfigFile, error) {
+	config := i.config.DeepCopy()
+	config.Labels = nil
+	if i.labels != nil {
+		config.Labels = make(map[string]string, len(i.labels))
+		for k, v := range i.labels {
+			config.Labels[k] = v
+		}
+	}
+	config.Env = nil
+	for k, v := range i.env {
+		config.Env = append(config.Env, k+"="+v)
+	}
+	sort.Strings(config.Env)
+	config.Entrypoint = i.entryPoint
+	config.Cmd = i.cmd
+	config.WorkingDir = i.workingDir
+	return &v1.ConfigFile{
+		Architecture: i.architecture,
+		Created:      v1.Time{Time: i.createdAt},
+		History:      i.history,
+		OS:           i.os,
+		OSVersion:    i.osVersion,
+		Variant:      i.variant,
+		Config:       *config,
+	}, nil
 }
 
 func (i *Image) CreatedAt() (time.Time, error) {
@@ -126,6 +171,12 @@ func (i *Image) Rebase(baseTopLayer string, newBase imgutil.Image) error {
 	return nil
 }
 
+func (i *Image) RebaseWithReport(baseTopLayer string, newBase imgutil.Image, ops ...imgutil.RebaseOption) (imgutil.RebaseReport, error) {
+	return imgutil.RebaseWithReport(i, baseTopLayer, newBase, func() error {
+		return i.Rebase(baseTopLayer, newBase)
+	}, ops...)
+}
+
 func (i *Image) SetLabel(k string, v string) error {
 	if i.labels == nil {
 		i.labels = map[string]string{}
@@ -139,6 +190,23 @@ func (i *Image) RemoveLabel(key string) error {
 	return nil
 }
 
+func (i *Image) SetAnnotation(k string, v string) error {
+	if k == "org.opencontainers.image.ref.name" {
+		i.refName = v
+		return nil
+	}
+	i.annotations[k] = v
+	return nil
+}
+
+func (i *Image) RemoveAnnotation(key string) error {
+	if key == "org.opencontainers.image.ref.name" {
+		i.refName = ""
+	}
+	delete(i.annotations, key)
+	return nil
+}
+
 func (i *Image) SetEnv(k string, v string) error {
 	i.env[k] = v
 	return nil
@@ -241,16 +309,23 @@ func shaForFile(path string) (string, error) {
 func (i *Image) GetLayer(sha string) (io.ReadCloser, error) {
 	path, ok := i.layersMap[sha]
 	if !ok {
-		return nil, fmt.Errorf("failed to get layer with sha '%s'", sha)
+		return nil, imgutil.Errorf(imgutil.ErrLayerNotFound, "failed to get layer with sha '%s'", sha)
 	}
 
 	return os.Open(filepath.Clean(path))
 }
 
+func (i *Image) GetLayerContext(ctx context.Context, sha string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return i.GetLayer(sha)
+}
+
 func (i *Image) ReuseLayer(sha string) error {
 	prevLayer, ok := i.prevLayersMap[sha]
 	if !ok {
-		return fmt.Errorf("image does not have previous layer with sha '%s'", sha)
+		return imgutil.Errorf(imgutil.ErrLayerNotFound, "image does not have previous layer with sha '%s'", sha)
 	}
 	i.reusedLayers = append(i.reusedLayers, sha)
 	i.layersMap[sha] = prevLayer
@@ -269,11 +344,31 @@ func (i *Image) Save(additionalNames ...string) error {
 	return i.SaveAs(i.Name(), additionalNames...)
 }
 
+func (i *Image) SaveContext(ctx context.Context, additionalNames ...string) error {
+	return i.SaveAsContext(ctx, i.Name(), additionalNames...)
+}
+
+func (i *Image) SaveAsContext(ctx context.Context, name string, additionalNames ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return i.SaveAs(name, additionalNames...)
+}
+
 func (i *Image) SaveAs(name string, additionalNames ...string) error {
+	_, err := i.SaveAsWithResult(name, additionalNames...)
+	return err
+}
+
+func (i *Image) SaveWithResult(additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return i.SaveAsWithResult(i.Name(), additionalNames...)
+}
+
+func (i *Image) SaveAsWithResult(name string, additionalNames ...string) ([]imgutil.SaveResult, error) {
 	var err error
 	i.layerDir, err = os.MkdirTemp("", "fake-image")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for sha, path := range i.layersMap {
@@ -288,29 +383,29 @@ func (i *Image) SaveAs(name string, additionalNames ...string) error {
 	}
 
 	allNames := append([]string{name}, additionalNames...)
-	if i.refName != "" {
-		i.savedAnnotations["org.opencontainers.image.ref.name"] = i.refName
+	i.savedAnnotations, _ = i.Annotations()
+
+	var imageID string
+	if i.identifier != nil {
+		imageID = i.identifier.String()
 	}
 
-	var errs []imgutil.SaveDiagnostic
+	var results []imgutil.SaveResult
 	for _, n := range allNames {
 		_, err := registryName.ParseReference(n, registryName.WeakValidation)
 		if err != nil {
-			errs = append(errs, imgutil.SaveDiagnostic{ImageName: n, Cause: err})
+			results = append(results, imgutil.SaveResult{Name: n, Err: err})
 		} else {
 			i.savedNames[n] = true
+			results = append(results, imgutil.SaveResult{Name: n, ImageID: imageID})
 		}
 	}
 
-	if len(errs) > 0 {
-		return imgutil.SaveError{Errors: errs}
-	}
-
-	return nil
+	return results, imgutil.SaveErrorFrom(results)
 }
 
 func (i *Image) SaveFile() (string, error) {
-	return "", errors.New("not yet implemented")
+	return "", imgutil.Errorf(imgutil.ErrNotImplemented, "not yet implemented")
 }
 
 func (i *Image) copyLayer(path, newPath string) error {
@@ -338,10 +433,50 @@ func (i *Image) Delete() error {
 	return nil
 }
 
+func (i *Image) DeleteContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return i.Delete()
+}
+
+// MutateConfigFile calls withFunc on the config file of the fake, and sets the fields of the fake from the result.
+func (i *Image) MutateConfigFile(withFunc func(c *v1.ConfigFile)) error {
+	configFile, err := i.ConfigFile()
+	if err != nil {
+		return err
+	}
+	withFunc(configFile)
+	i.architecture = configFile.Architecture
+	i.createdAt = configFile.Created.Time
+	i.history = configFile.History
+	i.os = configFile.OS
+	i.osVersion = configFile.OSVersion
+	i.variant = configFile.Variant
+	i.labels = configFile.Config.Labels
+	i.env = map[string]string{}
+	for _, envVar := range configFile.Config.Env {
+		parts := strings.SplitN(envVar, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid env var %q", envVar)
+		}
+		i.env[parts[0]] = parts[1]
+	}
+	i.entryPoint = configFile.Config.Entrypoint
+	i.cmd = configFile.Config.Cmd
+	i.workingDir = configFile.Config.WorkingDir
+	i.config = configFile.Config
+	return nil
+}
+
 func (i *Image) Found() bool {
 	return !i.deleted
 }
 
+func (i *Image) FoundContext(_ context.Context) bool {
+	return i.Found()
+}
+
 func (i *Image) Valid() bool {
 	return !i.deleted
 }
@@ -2,6 +2,7 @@ package fakes_test
 
 import (
 	"archive/tar"
+	"errors"
 	"fmt"
 
 	"os"
@@ -9,11 +10,14 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
 
 	"github.com/buildpacks/imgutil"
 	"github.com/buildpacks/imgutil/fakes"
+	"github.com/buildpacks/imgutil/local"
 	h "github.com/buildpacks/imgutil/testhelpers"
 )
 
@@ -76,6 +80,37 @@ func testFake(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#SaveWithResult", func() {
+		it("returns the outcome of saving the image as each name", func() {
+			repoName := newRepoName()
+			badImageName := repoName + ":🧨"
+			image := fakes.NewImage(repoName, "", local.IDIdentifier{ImageID: "some-image-id"})
+
+			results, err := image.SaveWithResult(badImageName)
+			h.AssertError(t, err, "failed to write image to the following tags")
+
+			h.AssertEq(t, len(results), 2)
+			h.AssertEq(t, results[0].Name, repoName)
+			h.AssertEq(t, results[0].ImageID, "some-image-id")
+			h.AssertNil(t, results[0].Err)
+			h.AssertEq(t, results[1].Name, badImageName)
+			h.AssertError(t, results[1].Err, "could not parse reference")
+			h.AssertEq(t, name.IsErrBadName(err), true)
+		})
+	})
+
+	when("#GetLayer", func() {
+		when("the image doesn't have the layer", func() {
+			it("returns an error matching imgutil.ErrLayerNotFound", func() {
+				image := fakes.NewImage(newRepoName(), "", nil)
+
+				_, err := image.GetLayer("some-missing-sha")
+				h.AssertError(t, err, "failed to get layer with sha 'some-missing-sha'")
+				h.AssertEq(t, errors.Is(err, imgutil.ErrLayerNotFound), true)
+			})
+		})
+	})
+
 	when("#FindLayerWithPath", func() {
 		var (
 			image      *fakes.Image
@@ -147,6 +182,45 @@ Layers
 			h.AssertEq(t, annotations["org.opencontainers.image.ref.name"], refName)
 		})
 	})
+
+	when("#SetAnnotation", func() {
+		var repoName = newRepoName()
+
+		it("saves the annotations", func() {
+			image := fakes.NewImage(repoName, "", nil)
+			h.AssertNil(t, image.SetAnnotation("some-key", "some-value"))
+			h.AssertNil(t, image.SetAnnotation("some-other-key", "some-other-value"))
+			h.AssertNil(t, image.RemoveAnnotation("some-other-key"))
+
+			_ = image.Save()
+
+			h.AssertEq(t, image.SavedAnnotations(), map[string]string{"some-key": "some-value"})
+		})
+	})
+
+	when("#MutateConfigFile", func() {
+		it("sets the fields of the image from the config file", func() {
+			image := fakes.NewImage(newRepoName(), "", nil)
+			h.AssertNil(t, image.SetEnv("SOME_KEY", "some-value"))
+
+			h.AssertNil(t, image.MutateConfigFile(func(c *v1.ConfigFile) {
+				c.Config.Env = append(c.Config.Env, "OTHER_KEY=other=value")
+				c.Config.User = "some-user"
+				c.Variant = "v8"
+			}))
+
+			env, err := image.Env("OTHER_KEY")
+			h.AssertNil(t, err)
+			h.AssertEq(t, env, "other=value")
+			variant, err := image.Variant()
+			h.AssertNil(t, err)
+			h.AssertEq(t, variant, "v8")
+			configFile, err := image.ConfigFile()
+			h.AssertNil(t, err)
+			h.AssertEq(t, configFile.Config.Env, []string{"OTHER_KEY=other=value", "SOME_KEY=some-value"})
+			h.AssertEq(t, configFile.Config.User, "some-user")
+		})
+	})
 }
 
 func createLayerTar(contents map[string]string) (string, error) {
@@ -1,47 +1,71 @@
 package imgutil
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 )
 
 type Image interface {
 	// getters
 
+	// Annotations returns the annotations of the image manifest.
+	Annotations() (map[string]string, error)
+	// Deprecated: use ConfigFile instead.
 	Architecture() (string, error)
+	// ConfigFile returns a copy of the config file of the image.
+	ConfigFile() (*v1.ConfigFile, error)
+	// Deprecated: use ConfigFile instead.
 	CreatedAt() (time.Time, error)
+	// Deprecated: use ConfigFile instead.
 	Entrypoint() ([]string, error)
 	Env(key string) (string, error)
 	// Found tells whether the image exists in the repository by `Name()`.
 	Found() bool
+	// FoundContext is like Found, but aborts any request made to the backing store when ctx is done.
+	FoundContext(ctx context.Context) bool
 	GetAnnotateRefName() (string, error)
 	// GetLayer retrieves layer by diff id. Returns a reader of the uncompressed contents of the layer.
 	GetLayer(diffID string) (io.ReadCloser, error)
+	// GetLayerContext is like GetLayer, but aborts any request made to the backing store when ctx is done.
+	GetLayerContext(ctx context.Context, diffID string) (io.ReadCloser, error)
+	// Deprecated: use ConfigFile instead.
 	History() ([]v1.History, error)
 	Identifier() (Identifier, error)
 	// Kind exposes the type of image that backs the imgutil.Image implementation.
 	// It could be `local`, `locallayout`, `remote`, or `layout`.
 	Kind() string
+	// Deprecated: use ConfigFile instead.
 	Label(string) (string, error)
+	// Deprecated: use ConfigFile instead.
 	Labels() (map[string]string, error)
 	// ManifestSize returns the size of the manifest. If a manifest doesn't exist, it returns 0.
+	//
+	// Deprecated: use UnderlyingImage instead.
 	ManifestSize() (int64, error)
 	Name() string
+	// Deprecated: use ConfigFile instead.
 	OS() (string, error)
+	// Deprecated: use ConfigFile instead.
 	OSVersion() (string, error)
 	// TopLayer returns the diff id for the top layer
 	TopLayer() (string, error)
 	UnderlyingImage() v1.Image
 	// Valid returns true if the image is well-formed (e.g. all manifest layers exist on the registry).
 	Valid() bool
+	// Deprecated: use ConfigFile instead.
 	Variant() (string, error)
+	// Deprecated: use ConfigFile instead.
 	WorkingDir() (string, error)
 
 	// setters
@@ -49,15 +73,24 @@ type Image interface {
 	// AnnotateRefName set a value for the `org.opencontainers.image.ref.name` annotation
 	AnnotateRefName(refName string) error
 	Rename(name string)
+	// SetAnnotation sets an annotation on the image manifest, replacing any existing value for the key.
+	SetAnnotation(key, value string) error
+	// Deprecated: use MutateConfigFile instead.
 	SetArchitecture(string) error
+	// Deprecated: use MutateConfigFile instead.
 	SetCmd(...string) error
+	// Deprecated: use MutateConfigFile instead.
 	SetEntrypoint(...string) error
 	SetEnv(string, string) error
+	// Deprecated: use MutateConfigFile instead.
 	SetHistory([]v1.History) error
 	SetLabel(string, string) error
 	SetOS(string) error
+	// Deprecated: use MutateConfigFile instead.
 	SetOSVersion(string) error
+	// Deprecated: use MutateConfigFile instead.
 	SetVariant(string) error
+	// Deprecated: use MutateConfigFile instead.
 	SetWorkingDir(string) error
 
 	// modifiers
@@ -66,25 +99,57 @@ type Image interface {
 	AddLayerWithDiffID(path, diffID string) error
 	AddLayerWithDiffIDAndHistory(path, diffID string, history v1.History) error
 	Delete() error
+	// DeleteContext is like Delete, but aborts any request made to the backing store when ctx is done.
+	DeleteContext(ctx context.Context) error
+	// MutateConfigFile calls withFunc on the config file of the image, and sets the result as the config file of the image.
+	// Backends may reject changes they cannot represent, such as a new OS for an image in a docker daemon.
+	MutateConfigFile(withFunc func(c *v1.ConfigFile)) error
 	Rebase(string, Image) error
+	RemoveAnnotation(key string) error
 	RemoveLabel(string) error
 	ReuseLayer(diffID string) error
 	ReuseLayerWithHistory(diffID string, history v1.History) error
 	// Save saves the image as `Name()` and any additional names provided to this method.
 	Save(additionalNames ...string) error
+	// SaveContext is like Save, but aborts the save when ctx is done.
+	SaveContext(ctx context.Context, additionalNames ...string) error
 	// SaveAs ignores the image `Name()` method and saves the image according to name & additional names provided to this method
 	SaveAs(name string, additionalNames ...string) error
+	// SaveAsContext is like SaveAs, but aborts the save when ctx is done.
+	SaveAsContext(ctx context.Context, name string, additionalNames ...string) error
+	// SaveWithResult is like Save, but also returns the outcome of saving the image as each name, in the order requested.
+	SaveWithResult(additionalNames ...string) ([]SaveResult, error)
+	// SaveAsWithResult is like SaveAs, but also returns the outcome of saving the image as each name, in the order requested.
+	SaveAsWithResult(name string, additionalNames ...string) ([]SaveResult, error)
 	// SaveFile saves the image as a docker archive and provides the filesystem location
 	SaveFile() (string, error)
 }
 
 type Identifier fmt.Stringer
 
-// Platform represents the target arch/os/os_version for an image construction and querying.
+// Platform represents the target arch/os/os_version/variant/os_features for an image construction and querying.
 type Platform struct {
 	Architecture string
 	OS           string
 	OSVersion    string
+	Variant      string
+	osFeatures   string // sorted and comma-separated, so that platforms can be compared
+}
+
+// OSFeatures returns the features of the OS required by the platform, such as "win32k".
+func (p Platform) OSFeatures() []string {
+	if p.osFeatures == "" {
+		return nil
+	}
+	return strings.Split(p.osFeatures, ",")
+}
+
+// WithOSFeatures returns a copy of the platform that requires the given features of the OS.
+func (p Platform) WithOSFeatures(features ...string) Platform {
+	sorted := append([]string(nil), features...)
+	sort.Strings(sorted)
+	p.osFeatures = strings.Join(sorted, ",")
+	return p
 }
 
 type MediaTypes int
@@ -129,6 +194,18 @@ func (t MediaTypes) LayerType() types.MediaType {
 	}
 }
 
+// IndexType returns the index media type matching the requested media types.
+func (t MediaTypes) IndexType() types.MediaType {
+	switch t {
+	case OCITypes:
+		return types.OCIImageIndex
+	case DockerTypes:
+		return types.DockerManifestList
+	default:
+		return ""
+	}
+}
+
 // OverrideMediaTypes mutates the provided v1.Image to use the desired media types
 // in the image manifest and config files (including the layers referenced in the manifest)
 func OverrideMediaTypes(image v1.Image, mediaTypes MediaTypes) (v1.Image, error) {
@@ -181,6 +258,78 @@ func OverrideHistoryIfNeeded(image v1.Image) (v1.Image, error) {
 	return mutate.ConfigFile(image, configFile)
 }
 
+// OverrideAnnotations returns a v1.Image whose manifest has exactly the provided annotations.
+// Unlike mutate.Annotations, which merges annotations, existing annotations not in the map are removed.
+func OverrideAnnotations(image v1.Image, annotations map[string]string) v1.Image {
+	return &annotatedImage{Image: image, annotations: annotations}
+}
+
+type annotatedImage struct {
+	v1.Image
+	annotations map[string]string
+}
+
+func (i *annotatedImage) Digest() (v1.Hash, error) {
+	return partial.Digest(i)
+}
+
+func (i *annotatedImage) Manifest() (*v1.Manifest, error) {
+	manifest, err := i.Image.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	manifest = manifest.DeepCopy()
+	manifest.Annotations = i.annotations
+	return manifest, nil
+}
+
+func (i *annotatedImage) RawManifest() ([]byte, error) {
+	manifest, err := i.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(manifest)
+}
+
+func (i *annotatedImage) Size() (int64, error) {
+	return partial.Size(i)
+}
+
+// ValidateConfigFileMutation returns an error if a call to MutateConfigFile that turned before into after changed the
+// layers of the image, which can only be changed through AddLayer or Rebase, or changed the history of the image
+// so that it no longer has an entry for each layer. Entries for empty layers are not counted, and an empty history is
+// allowed.
+func ValidateConfigFileMutation(before, after *v1.ConfigFile) error {
+	if !equalRootFS(before.RootFS, after.RootFS) {
+		return Errorf(ErrNotImplemented, "changing the layers of an image through its config file is not supported")
+	}
+	if len(after.History) == len(before.History) || len(after.History) == 0 {
+		return nil
+	}
+	var nLayers int
+	for _, h := range after.History {
+		if !h.EmptyLayer {
+			nLayers++
+		}
+	}
+	if nLayers != len(after.RootFS.DiffIDs) {
+		return fmt.Errorf("invalid history: must have an entry for each of the %d layers, got %d", len(after.RootFS.DiffIDs), nLayers)
+	}
+	return nil
+}
+
+func equalRootFS(a, b v1.RootFS) bool {
+	if a.Type != b.Type || len(a.DiffIDs) != len(b.DiffIDs) {
+		return false
+	}
+	for idx := range a.DiffIDs {
+		if a.DiffIDs[idx] != b.DiffIDs[idx] {
+			return false
+		}
+	}
+	return true
+}
+
 func NormalizedHistory(history []v1.History, nLayers int) []v1.History {
 	if history == nil {
 		return make([]v1.History, nLayers)
@@ -233,3 +382,73 @@ func (e SaveError) Error() string {
 	}
 	return fmt.Sprintf("failed to write image to the following tags: %s", strings.Join(errors, ","))
 }
+
+// Unwrap returns the cause of each failure, so that errors.Is and errors.As match any of them.
+func (e SaveError) Unwrap() []error {
+	var causes []error
+	for _, d := range e.Errors {
+		causes = append(causes, d.Cause)
+	}
+	return causes
+}
+
+// SaveResult describes the outcome of saving an image as one of the requested names.
+type SaveResult struct {
+	// Name is the name the image was saved as.
+	Name string
+	// Digest, Size and MediaType describe the saved image manifest.
+	// They are empty for images saved to a docker daemon, which does not store the manifest.
+	Digest    v1.Hash
+	Size      int64
+	MediaType types.MediaType
+	// ImageID is the digest of the image config file.
+	ImageID string
+	// MountedLayers and UploadedLayers are the digests of the layers that were mounted from another repository
+	// and pushed, respectively, when the image was saved to a registry, in the order of the image manifest.
+	// Layers that the registry already had are in neither.
+	MountedLayers  []v1.Hash
+	UploadedLayers []v1.Hash
+	// Err is the error that occurred when saving the image as Name, if any.
+	Err error
+}
+
+// NewSaveResult returns a SaveResult describing the manifest and config of the provided image.
+func NewSaveResult(name string, image v1.Image) (SaveResult, error) {
+	digest, err := image.Digest()
+	if err != nil {
+		return SaveResult{}, fmt.Errorf("getting manifest digest: %w", err)
+	}
+	size, err := image.Size()
+	if err != nil {
+		return SaveResult{}, fmt.Errorf("getting manifest size: %w", err)
+	}
+	mediaType, err := image.MediaType()
+	if err != nil {
+		return SaveResult{}, fmt.Errorf("getting manifest media type: %w", err)
+	}
+	configName, err := image.ConfigName()
+	if err != nil {
+		return SaveResult{}, fmt.Errorf("getting config digest: %w", err)
+	}
+	return SaveResult{
+		Name:      name,
+		Digest:    digest,
+		Size:      size,
+		MediaType: mediaType,
+		ImageID:   configName.String(),
+	}, nil
+}
+
+// SaveErrorFrom returns a SaveError with a diagnostic for each result that failed, or nil if every name was saved.
+func SaveErrorFrom(results []SaveResult) error {
+	var diagnostics []SaveDiagnostic
+	for _, result := range results {
+		if result.Err != nil {
+			diagnostics = append(diagnostics, SaveDiagnostic{ImageName: result.Name, Cause: result.Err})
+		}
+	}
+	if len(diagnostics) > 0 {
+		return SaveError{Errors: diagnostics}
+	}
+	return nil
+}
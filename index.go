@@ -0,0 +1,263 @@
+package imgutil
+
+import (
+	"errors"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/match"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+)
+
+// ImageIndex represents a multi-platform image: a list of image manifests,
+// each of which may be annotated with the platform it targets.
+type ImageIndex interface {
+	// getters
+
+	// Annotations returns the annotations on the index descriptor of the manifest with the given digest.
+	Annotations(digest v1.Hash) (map[string]string, error)
+	// Manifests returns the descriptors of all the manifests referenced by the index.
+	Manifests() ([]v1.Descriptor, error)
+	Name() string
+	// Platform returns the platform on the index descriptor of the manifest with the given digest.
+	Platform(digest v1.Hash) (Platform, error)
+	UnderlyingIndex() v1.ImageIndex
+
+	// setters
+
+	SetAnnotations(digest v1.Hash, annotations map[string]string) error
+	SetPlatform(digest v1.Hash, platform Platform) error
+
+	// modifiers
+
+	// Add appends the manifest of the provided image to the index, using the image config to describe its platform.
+	// If the index already references the image manifest, it is replaced.
+	Add(image Image) error
+	Remove(digest v1.Hash) error
+	// Save saves the index as `Name()` and any additional names provided to this method.
+	Save(additionalNames ...string) error
+}
+
+type IndexOptions struct {
+	MediaTypes MediaTypes
+
+	// These options are specified in each implementation's index constructor
+	BaseIndex v1.ImageIndex
+}
+
+// CNBIndex wraps a v1.ImageIndex and provides most of the methods necessary for the index to satisfy the ImageIndex interface.
+// Specific implementations will need to supply Save().
+type CNBIndex struct {
+	index v1.ImageIndex // the working index
+	// required
+	repoName string
+}
+
+func NewCNBIndex(repoName string, options IndexOptions) (*CNBIndex, error) {
+	idx := &CNBIndex{
+		index:    options.BaseIndex,
+		repoName: repoName,
+	}
+	if idx.index == nil {
+		idx.index = empty.Index
+		if options.MediaTypes == MissingTypes || options.MediaTypes == DefaultTypes {
+			options.MediaTypes = OCITypes
+		}
+	}
+	if indexType := options.MediaTypes.IndexType(); indexType != "" {
+		idx.index = mutate.IndexMediaType(idx.index, indexType)
+	}
+	return idx, nil
+}
+
+// getters
+
+func (i *CNBIndex) Annotations(digest v1.Hash) (map[string]string, error) {
+	desc, err := i.descriptor(digest)
+	if err != nil {
+		return nil, err
+	}
+	return desc.Annotations, nil
+}
+
+func (i *CNBIndex) Manifests() ([]v1.Descriptor, error) {
+	indexManifest, err := getIndexManifest(i.index)
+	if err != nil {
+		return nil, err
+	}
+	return indexManifest.Manifests, nil
+}
+
+func (i *CNBIndex) Name() string {
+	return i.repoName
+}
+
+func (i *CNBIndex) Platform(digest v1.Hash) (Platform, error) {
+	desc, err := i.descriptor(digest)
+	if err != nil {
+		return Platform{}, err
+	}
+	if desc.Platform == nil {
+		return Platform{}, nil
+	}
+	return Platform{
+		Architecture: desc.Platform.Architecture,
+		OS:           desc.Platform.OS,
+		OSVersion:    desc.Platform.OSVersion,
+		Variant:      desc.Platform.Variant,
+	}.WithOSFeatures(desc.Platform.OSFeatures...), nil
+}
+
+// UnderlyingIndex is used to expose a v1.ImageIndex from an imgutil.ImageIndex.
+func (i *CNBIndex) UnderlyingIndex() v1.ImageIndex {
+	return i.index
+}
+
+// setters
+
+func (i *CNBIndex) SetAnnotations(digest v1.Hash, annotations map[string]string) error {
+	return i.mutateDescriptor(digest, func(desc *v1.Descriptor) {
+		desc.Annotations = annotations
+	})
+}
+
+func (i *CNBIndex) SetPlatform(digest v1.Hash, platform Platform) error {
+	return i.mutateDescriptor(digest, func(desc *v1.Descriptor) {
+		if desc.Platform == nil {
+			desc.Platform = &v1.Platform{}
+		}
+		desc.Platform.Architecture = platform.Architecture
+		desc.Platform.OS = platform.OS
+		desc.Platform.OSVersion = platform.OSVersion
+		desc.Platform.Variant = platform.Variant
+		desc.Platform.OSFeatures = platform.OSFeatures()
+	})
+}
+
+// modifiers
+
+func (i *CNBIndex) Add(image Image) error {
+	img := image.UnderlyingImage()
+	if img == nil {
+		return fmt.Errorf("image %q of kind %q cannot be added to an index", image.Name(), image.Kind())
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return err
+	}
+	configFile, err := getConfigFile(img)
+	if err != nil {
+		return err
+	}
+	mediaType, err := img.MediaType()
+	if err != nil {
+		return err
+	}
+	if i.contains(digest) {
+		i.index = mutate.RemoveManifests(i.index, match.Digests(digest))
+	}
+	i.index = mutate.AppendManifests(i.index, mutate.IndexAddendum{
+		Add: img,
+		Descriptor: v1.Descriptor{
+			MediaType: mediaType,
+			Platform: &v1.Platform{
+				Architecture: configFile.Architecture,
+				OS:           configFile.OS,
+				OSVersion:    configFile.OSVersion,
+				Variant:      configFile.Variant,
+				OSFeatures:   configFile.OSFeatures,
+			},
+		},
+	})
+	return nil
+}
+
+func (i *CNBIndex) Remove(digest v1.Hash) error {
+	if !i.contains(digest) {
+		return fmt.Errorf("failed to find manifest with digest %s in index %q", digest.String(), i.Name())
+	}
+	i.index = mutate.RemoveManifests(i.index, match.Digests(digest))
+	return nil
+}
+
+// helpers
+
+func (i *CNBIndex) contains(digest v1.Hash) bool {
+	_, err := i.descriptor(digest)
+	return err == nil
+}
+
+func (i *CNBIndex) descriptor(digest v1.Hash) (*v1.Descriptor, error) {
+	indexManifest, err := getIndexManifest(i.index)
+	if err != nil {
+		return nil, err
+	}
+	for _, desc := range indexManifest.Manifests {
+		if desc.Digest == digest {
+			return &desc, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to find manifest with digest %s in index %q", digest.String(), i.Name())
+}
+
+// mutateDescriptor rebuilds the index with the descriptor matching the provided digest modified by withFunc,
+// keeping the order of the manifests in the index.
+func (i *CNBIndex) mutateDescriptor(digest v1.Hash, withFunc func(desc *v1.Descriptor)) error {
+	indexManifest, err := getIndexManifest(i.index)
+	if err != nil {
+		return err
+	}
+	var (
+		adds  []mutate.IndexAddendum
+		found bool
+	)
+	for _, desc := range indexManifest.Manifests {
+		desc := desc
+		appendable, err := i.appendable(desc)
+		if err != nil {
+			return err
+		}
+		if desc.Digest == digest {
+			withFunc(&desc)
+			found = true
+		}
+		adds = append(adds, mutate.IndexAddendum{Add: appendable, Descriptor: desc})
+	}
+	if !found {
+		return fmt.Errorf("failed to find manifest with digest %s in index %q", digest.String(), i.Name())
+	}
+	base := mutate.RemoveManifests(i.index, func(v1.Descriptor) bool { return true })
+	i.index = mutate.AppendManifests(base, adds...)
+	return nil
+}
+
+func (i *CNBIndex) appendable(desc v1.Descriptor) (mutate.Appendable, error) {
+	switch {
+	case desc.MediaType.IsIndex():
+		index, err := i.index.ImageIndex(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		return index, nil
+	case desc.MediaType.IsImage():
+		image, err := i.index.Image(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		return image, nil
+	default:
+		return nil, fmt.Errorf("unsupported media type %q for manifest %s", desc.MediaType, desc.Digest.String())
+	}
+}
+
+func getIndexManifest(index v1.ImageIndex) (*v1.IndexManifest, error) {
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	if indexManifest == nil {
+		return nil, errors.New("missing index manifest")
+	}
+	return indexManifest, nil
+}
@@ -0,0 +1,148 @@
+package layout
+
+import (
+	"context"
+	"io"
+
+	"github.com/buildpacks/imgutil"
+)
+
+// CNBImage wraps an imgutil.CNBImageCore and implements the methods needed to complete the imgutil.Image interface
+// for images in an OCI layout. Unlike Image, it shares the getters, setters and modifiers of the other backends.
+// The `org.opencontainers.image.ref.name` annotation is kept on the image manifest, and is also set on the image
+// descriptor in the layout index when the image is saved.
+type CNBImage struct {
+	*imgutil.CNBImageCore
+}
+
+var _ imgutil.Image = &CNBImage{}
+var _ imgutil.RebaseReporter = &CNBImage{}
+
+// NewCNBImage returns a new image built on imgutil.CNBImageCore that can be modified and saved to an OCI layout.
+// It accepts the same options as NewImage.
+func NewCNBImage(path string, ops ...ImageOption) (*CNBImage, error) {
+	imageOpts := &options{}
+	for _, op := range ops {
+		if err := op(imageOpts); err != nil {
+			return nil, err
+		}
+	}
+
+	platform := defaultPlatform()
+	if (imageOpts.platform != imgutil.Platform{}) {
+		platform = imageOpts.platform
+	}
+
+	var (
+		err     error
+		options = imgutil.ImageOptions{
+			BaseImage:       imageOpts.baseImage,
+			CreatedAt:       imageOpts.createdAt,
+			Platform:        platform,
+			PreserveHistory: imageOpts.withHistory,
+			MediaTypes:      imageOpts.mediaTypes,
+			Progress:        imageOpts.progress,
+		}
+	)
+	if imageOpts.prevImagePath != "" {
+		if options.PreviousImage, err = newV1Image(imageOpts.prevImagePath, platform, imageOpts.withHistory); err != nil {
+			return nil, err
+		}
+	}
+	if imageOpts.baseImagePath != "" {
+		if options.BaseImage, err = newV1Image(imageOpts.baseImagePath, platform, imageOpts.withHistory); err != nil {
+			return nil, err
+		}
+	}
+	if options.BaseImage == nil {
+		if options.BaseImage, err = emptyImage(platform); err != nil {
+			return nil, err
+		}
+		if options.MediaTypes == imgutil.MissingTypes {
+			options.MediaTypes = imgutil.OCITypes
+		}
+	}
+
+	cnbImage, err := imgutil.NewCNBImage(path, &Store{progress: imageOpts.progress}, options)
+	if err != nil {
+		return nil, err
+	}
+	return &CNBImage{CNBImageCore: cnbImage}, nil
+}
+
+func (i *CNBImage) Found() bool {
+	return i.FoundContext(context.Background())
+}
+
+// FoundContext is the same as Found, as checking for the image on disk does not block.
+func (i *CNBImage) FoundContext(ctx context.Context) bool {
+	return imgutil.ContextStore(i.Store).ContainsContext(ctx, i.Name())
+}
+
+// Identifier returns the path of the image followed by "@" and the digest of the image.
+func (i *CNBImage) Identifier() (imgutil.Identifier, error) {
+	hash, err := i.Image.Digest()
+	if err != nil {
+		return nil, err
+	}
+	return newLayoutIdentifier(i.Name(), hash)
+}
+
+// GetLayer returns a reader of the uncompressed contents of the layer with the given diff ID.
+func (i *CNBImage) GetLayer(diffID string) (io.ReadCloser, error) {
+	return i.GetLayerContext(context.Background(), diffID)
+}
+
+func (i *CNBImage) GetLayerContext(ctx context.Context, diffID string) (io.ReadCloser, error) {
+	return (&Image{Image: i.Image, path: i.Name()}).GetLayerContext(ctx, diffID)
+}
+
+// modifiers
+
+func (i *CNBImage) Delete() error {
+	return i.DeleteContext(context.Background())
+}
+
+func (i *CNBImage) DeleteContext(ctx context.Context) error {
+	return imgutil.ContextStore(i.Store).DeleteContext(ctx, i.Name())
+}
+
+// RebaseWithReport is like Rebase, but first validates the new base as requested and reports what the rebase changed.
+func (i *CNBImage) RebaseWithReport(baseTopLayerDiffID string, withNewBase imgutil.Image, ops ...imgutil.RebaseOption) (imgutil.RebaseReport, error) {
+	return imgutil.RebaseWithReport(i, baseTopLayerDiffID, withNewBase, func() error {
+		return i.Rebase(baseTopLayerDiffID, withNewBase)
+	}, ops...)
+}
+
+func (i *CNBImage) Save(additionalNames ...string) error {
+	return i.SaveAsContext(context.Background(), i.Name(), additionalNames...)
+}
+
+func (i *CNBImage) SaveContext(ctx context.Context, additionalNames ...string) error {
+	return i.SaveAsContext(ctx, i.Name(), additionalNames...)
+}
+
+// SaveAs ignores the image `Name()` method and saves the image according to name & additional names provided to this method
+func (i *CNBImage) SaveAs(name string, additionalNames ...string) error {
+	return i.SaveAsContext(context.Background(), name, additionalNames...)
+}
+
+// SaveAsContext is like SaveAs, but stops before writing the next path once ctx is done.
+func (i *CNBImage) SaveAsContext(ctx context.Context, name string, additionalNames ...string) error {
+	_, err := imgutil.ContextStore(i.Store).SaveContext(ctx, i, name, additionalNames...)
+	return err
+}
+
+func (i *CNBImage) SaveWithResult(additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return imgutil.ContextStore(i.Store).SaveContext(context.Background(), i, i.Name(), additionalNames...)
+}
+
+func (i *CNBImage) SaveAsWithResult(name string, additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return imgutil.ContextStore(i.Store).SaveContext(context.Background(), i, name, additionalNames...)
+}
+
+// SaveFile saves the image as a docker archive and provides the filesystem location.
+// Every layer must be present in the layout, so images from sparse layouts cannot be saved.
+func (i *CNBImage) SaveFile() (string, error) {
+	return i.Store.SaveFile(i, i.Name())
+}
@@ -0,0 +1,127 @@
+package layout_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/layout"
+	h "github.com/buildpacks/imgutil/testhelpers"
+)
+
+func TestCNBImage(t *testing.T) {
+	spec.Run(t, "CNBImage", testCNBImage, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testCNBImage(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir    string
+		imagePath string
+	)
+
+	it.Before(func() {
+		tmpDir = t.TempDir()
+		imagePath = filepath.Join(tmpDir, "some-image")
+	})
+
+	when("#Save", func() {
+		it("writes the image and its ref name to the layout", func() {
+			image, err := layout.NewCNBImage(imagePath)
+			h.AssertNil(t, err)
+			h.AssertEq(t, image.Kind(), "layout")
+			h.AssertEq(t, image.Found(), false)
+
+			layerPath, diffID, _ := h.RandomLayer(t, tmpDir)
+			h.AssertNil(t, image.AddLayer(layerPath))
+			h.AssertNil(t, image.SetLabel("some-label", "some-value"))
+			h.AssertNil(t, image.SetEnv("SOME_KEY", "some-value"))
+			h.AssertNil(t, image.AnnotateRefName("my-tag"))
+			h.AssertNil(t, image.Save())
+			h.AssertEq(t, image.Found(), true)
+
+			index := h.ReadIndexManifest(t, imagePath)
+			h.AssertEq(t, len(index.Manifests), 1)
+			h.AssertEq(t, index.Manifests[0].MediaType, imgutil.OCITypes.ManifestType())
+			h.AssertEqAnnotation(t, index.Manifests[0], "org.opencontainers.image.ref.name", "my-tag")
+			identifier, err := image.Identifier()
+			h.AssertNil(t, err)
+			h.AssertEq(t, identifier.String(), imagePath+"@"+index.Manifests[0].Digest.String())
+
+			// the saved image is read back as a base image
+			rebuilt, err := layout.NewCNBImage(filepath.Join(tmpDir, "other-image"), layout.FromBaseImagePath(imagePath))
+			h.AssertNil(t, err)
+			label, err := rebuilt.Label("some-label")
+			h.AssertNil(t, err)
+			h.AssertEq(t, label, "some-value")
+			env, err := rebuilt.Env("SOME_KEY")
+			h.AssertNil(t, err)
+			h.AssertEq(t, env, "some-value")
+			topLayer, err := rebuilt.TopLayer()
+			h.AssertNil(t, err)
+			h.AssertEq(t, topLayer, diffID)
+		})
+
+		it("reuses layers of the previous image", func() {
+			prevImage, err := layout.NewCNBImage(imagePath)
+			h.AssertNil(t, err)
+			layerPath, diffID, _ := h.RandomLayer(t, tmpDir)
+			h.AssertNil(t, prevImage.AddLayer(layerPath))
+			h.AssertNil(t, prevImage.Save())
+
+			image, err := layout.NewCNBImage(filepath.Join(tmpDir, "new-image"), layout.WithPreviousImage(imagePath))
+			h.AssertNil(t, err)
+			h.AssertNil(t, image.ReuseLayer(diffID))
+			h.AssertNil(t, image.Save())
+
+			_, configFile := h.ReadManifestAndConfigFile(t, filepath.Join(tmpDir, "new-image"))
+			h.AssertEq(t, len(configFile.RootFS.DiffIDs), 1)
+			h.AssertEq(t, configFile.RootFS.DiffIDs[0].String(), diffID)
+		})
+	})
+
+	when("the base image is in a sparse layout", func() {
+		it("saves the image without the missing layers, but not as an archive", func() {
+			sparseBaseImagePath := filepath.Join("testdata", "layout", "busybox-sparse")
+			image, err := layout.NewCNBImage(imagePath, layout.FromBaseImagePath(sparseBaseImagePath))
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, image.Save())
+			h.AssertEq(t, image.Found(), true)
+
+			_, err = image.SaveFile()
+			h.AssertEq(t, errors.Is(err, imgutil.ErrLayerNotFound), true)
+		})
+	})
+
+	when("#SaveFile", func() {
+		it("writes a docker archive", func() {
+			image, err := layout.NewCNBImage(imagePath)
+			h.AssertNil(t, err)
+			layerPath, _, _ := h.RandomLayer(t, tmpDir)
+			h.AssertNil(t, image.AddLayer(layerPath))
+
+			path, err := image.SaveFile()
+			h.AssertNil(t, err)
+			defer os.Remove(path)
+			_, err = os.Stat(path)
+			h.AssertNil(t, err)
+		})
+	})
+
+	when("#Delete", func() {
+		it("removes the layout", func() {
+			image, err := layout.NewCNBImage(imagePath)
+			h.AssertNil(t, err)
+			h.AssertNil(t, image.Save())
+			h.AssertEq(t, image.Found(), true)
+
+			h.AssertNil(t, image.Delete())
+			h.AssertEq(t, image.Found(), false)
+		})
+	})
+}
@@ -0,0 +1,24 @@
+package layout
+
+import (
+	"context"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/buildpacks/imgutil"
+)
+
+var _ imgutil.CopyTarget = &Image{}
+
+// SaveCopy writes image as is to the path of the image, and makes it the content of the image.
+func (i *Image) SaveCopy(ctx context.Context, image v1.Image) (imgutil.SaveResult, error) {
+	if err := ctx.Err(); err != nil {
+		return imgutil.SaveResult{}, err
+	}
+	i.Image = image
+	results, err := i.write(ctx, []string{i.path})
+	if err != nil {
+		return imgutil.SaveResult{}, err
+	}
+	return results[0], nil
+}
@@ -0,0 +1,62 @@
+package layout
+
+import (
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/imgutil"
+)
+
+// ImageIndex wraps an imgutil.CNBIndex and implements the methods needed to save it in OCI layout format.
+type ImageIndex struct {
+	*imgutil.CNBIndex
+}
+
+var _ imgutil.ImageIndex = &ImageIndex{}
+
+// NewIndex returns a new ImageIndex that can be modified and saved to the provided path.
+func NewIndex(path string, ops ...IndexOption) (*ImageIndex, error) {
+	indexOpts := &indexOptions{}
+	for _, op := range ops {
+		if err := op(indexOpts); err != nil {
+			return nil, err
+		}
+	}
+
+	var baseIndex v1.ImageIndex
+	if indexOpts.baseIndexPath != "" && ImageExists(indexOpts.baseIndexPath) {
+		layoutPath, err := FromPath(indexOpts.baseIndexPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "loading base index from path %q", indexOpts.baseIndexPath)
+		}
+		baseIndex, err = layoutPath.ImageIndex()
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading base index from path %q", indexOpts.baseIndexPath)
+		}
+	}
+
+	cnbIndex, err := imgutil.NewCNBIndex(path, imgutil.IndexOptions{
+		BaseIndex:  baseIndex,
+		MediaTypes: indexOpts.mediaTypes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ImageIndex{CNBIndex: cnbIndex}, nil
+}
+
+// Save writes the index, along with all the manifests and blobs it references, to `Name()` and any additional paths
+// provided to this method.
+func (i *ImageIndex) Save(additionalNames ...string) error {
+	var diagnostics []imgutil.SaveDiagnostic
+	for _, path := range append([]string{i.Name()}, additionalNames...) {
+		if _, err := Write(path, i.UnderlyingIndex()); err != nil {
+			diagnostics = append(diagnostics, imgutil.SaveDiagnostic{ImageName: path, Cause: err})
+		}
+	}
+	if len(diagnostics) > 0 {
+		return imgutil.SaveError{Errors: diagnostics}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package layout_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/layout"
+	h "github.com/buildpacks/imgutil/testhelpers"
+)
+
+func TestIndex(t *testing.T) {
+	spec.Run(t, "Index", testIndex, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testIndex(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir    string
+		indexPath string
+		amd64Img  *layout.Image
+		arm64Img  *layout.Image
+		err       error
+	)
+
+	newPlatformImage := func(platform imgutil.Platform) *layout.Image {
+		img, err := layout.NewImage(filepath.Join(tmpDir, platform.Architecture), layout.WithDefaultPlatform(platform))
+		h.AssertNil(t, err)
+		layerPath, _, _ := h.RandomLayer(t, tmpDir)
+		h.AssertNil(t, img.AddLayer(layerPath))
+		return img
+	}
+
+	digestOf := func(img *layout.Image) v1.Hash {
+		digest, err := img.UnderlyingImage().Digest()
+		h.AssertNil(t, err)
+		return digest
+	}
+
+	it.Before(func() {
+		tmpDir, err = os.MkdirTemp("", "layout-index")
+		h.AssertNil(t, err)
+		indexPath = filepath.Join(tmpDir, "index")
+
+		amd64Img = newPlatformImage(imgutil.Platform{OS: "linux", Architecture: "amd64"})
+		arm64Img = newPlatformImage(imgutil.Platform{OS: "linux", Architecture: "arm64"})
+	})
+
+	it.After(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	when("#NewIndex", func() {
+		it("defaults to an empty OCI image index", func() {
+			idx, err := layout.NewIndex(indexPath)
+			h.AssertNil(t, err)
+
+			manifests, err := idx.Manifests()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(manifests), 0)
+
+			mediaType, err := idx.UnderlyingIndex().MediaType()
+			h.AssertNil(t, err)
+			h.AssertEq(t, mediaType, types.OCIImageIndex)
+		})
+
+		when("#WithIndexMediaTypes", func() {
+			it("uses a docker manifest list", func() {
+				idx, err := layout.NewIndex(indexPath, layout.WithIndexMediaTypes(imgutil.DockerTypes))
+				h.AssertNil(t, err)
+
+				mediaType, err := idx.UnderlyingIndex().MediaType()
+				h.AssertNil(t, err)
+				h.AssertEq(t, mediaType, types.DockerManifestList)
+			})
+		})
+
+		when("#FromBaseIndexPath", func() {
+			it("loads the manifests of the existing index", func() {
+				base, err := layout.NewIndex(filepath.Join(tmpDir, "base-index"))
+				h.AssertNil(t, err)
+				h.AssertNil(t, base.Add(amd64Img))
+				h.AssertNil(t, base.Save())
+
+				idx, err := layout.NewIndex(indexPath, layout.FromBaseIndexPath(base.Name()))
+				h.AssertNil(t, err)
+
+				manifests, err := idx.Manifests()
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(manifests), 1)
+				h.AssertEq(t, manifests[0].Digest, digestOf(amd64Img))
+			})
+
+			it("ignores a missing index", func() {
+				idx, err := layout.NewIndex(indexPath, layout.FromBaseIndexPath(filepath.Join(tmpDir, "does-not-exist")))
+				h.AssertNil(t, err)
+
+				manifests, err := idx.Manifests()
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(manifests), 0)
+			})
+		})
+	})
+
+	when("#Add", func() {
+		it("adds the image manifests with their platforms", func() {
+			idx, err := layout.NewIndex(indexPath)
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, idx.Add(amd64Img))
+			h.AssertNil(t, idx.Add(arm64Img))
+
+			manifests, err := idx.Manifests()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(manifests), 2)
+			h.AssertEq(t, manifests[0].Digest, digestOf(amd64Img))
+			h.AssertEq(t, manifests[0].Platform.Architecture, "amd64")
+			h.AssertEq(t, manifests[1].Digest, digestOf(arm64Img))
+			h.AssertEq(t, manifests[1].Platform.Architecture, "arm64")
+		})
+
+		it("does not duplicate an existing manifest", func() {
+			idx, err := layout.NewIndex(indexPath)
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, idx.Add(amd64Img))
+			h.AssertNil(t, idx.Add(amd64Img))
+
+			manifests, err := idx.Manifests()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(manifests), 1)
+		})
+	})
+
+	when("#Remove", func() {
+		it("removes the manifest", func() {
+			idx, err := layout.NewIndex(indexPath)
+			h.AssertNil(t, err)
+			h.AssertNil(t, idx.Add(amd64Img))
+			h.AssertNil(t, idx.Add(arm64Img))
+
+			h.AssertNil(t, idx.Remove(digestOf(amd64Img)))
+
+			manifests, err := idx.Manifests()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(manifests), 1)
+			h.AssertEq(t, manifests[0].Digest, digestOf(arm64Img))
+		})
+
+		it("errors when the manifest is missing", func() {
+			idx, err := layout.NewIndex(indexPath)
+			h.AssertNil(t, err)
+
+			err = idx.Remove(digestOf(amd64Img))
+			h.AssertError(t, err, "failed to find manifest with digest")
+		})
+	})
+
+	when("#SetPlatform", func() {
+		it("updates the platform of the manifest and keeps the order of the index", func() {
+			idx, err := layout.NewIndex(indexPath)
+			h.AssertNil(t, err)
+			h.AssertNil(t, idx.Add(amd64Img))
+			h.AssertNil(t, idx.Add(arm64Img))
+
+			h.AssertNil(t, idx.SetPlatform(digestOf(amd64Img), imgutil.Platform{OS: "linux", Architecture: "amd64", OSVersion: "some-version"}))
+
+			platform, err := idx.Platform(digestOf(amd64Img))
+			h.AssertNil(t, err)
+			h.AssertEq(t, platform.OSVersion, "some-version")
+
+			manifests, err := idx.Manifests()
+			h.AssertNil(t, err)
+			h.AssertEq(t, manifests[0].Digest, digestOf(amd64Img))
+		})
+	})
+
+	when("#SetAnnotations", func() {
+		it("sets annotations on the manifest descriptor", func() {
+			idx, err := layout.NewIndex(indexPath)
+			h.AssertNil(t, err)
+			h.AssertNil(t, idx.Add(amd64Img))
+
+			h.AssertNil(t, idx.SetAnnotations(digestOf(amd64Img), map[string]string{"some-key": "some-value"}))
+
+			annotations, err := idx.Annotations(digestOf(amd64Img))
+			h.AssertNil(t, err)
+			h.AssertEq(t, annotations["some-key"], "some-value")
+		})
+	})
+
+	when("#Save", func() {
+		it("writes the index and the images to the layout path", func() {
+			idx, err := layout.NewIndex(indexPath)
+			h.AssertNil(t, err)
+			h.AssertNil(t, idx.Add(amd64Img))
+			h.AssertNil(t, idx.Add(arm64Img))
+
+			h.AssertNil(t, idx.Save())
+
+			indexManifest := h.ReadIndexManifest(t, indexPath)
+			h.AssertEq(t, len(indexManifest.Manifests), 2)
+			h.AssertPathExists(t, filepath.Join(indexPath, "blobs", "sha256", digestOf(amd64Img).Hex))
+			h.AssertPathExists(t, filepath.Join(indexPath, "blobs", "sha256", digestOf(arm64Img).Hex))
+		})
+	})
+}
@@ -2,6 +2,7 @@ package layout
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -19,6 +20,7 @@ import (
 )
 
 var _ imgutil.Image = (*Image)(nil)
+var _ imgutil.RebaseReporter = (*Image)(nil)
 
 type Image struct {
 	v1.Image
@@ -29,10 +31,24 @@ type Image struct {
 	refName             string // holds org.opencontainers.image.ref.name value
 	requestedMediaTypes imgutil.MediaTypes
 	withHistory         bool
+	progress            imgutil.ProgressHandler
 }
 
 // getters
 
+// Annotations returns the annotations of the image manifest, along with the `org.opencontainers.image.ref.name`
+// annotation that is saved on the image descriptor in the layout index.
+func (i *Image) Annotations() (map[string]string, error) {
+	annotations, err := i.manifestAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	if i.refName != "" {
+		annotations[ImageRefNameKey] = i.refName
+	}
+	return annotations, nil
+}
+
 func (i *Image) Architecture() (string, error) {
 	cfg, err := i.Image.ConfigFile()
 	if err != nil {
@@ -88,6 +104,11 @@ func (i *Image) Found() bool {
 	return ImageExists(i.path)
 }
 
+// FoundContext is the same as Found, as checking for the image on disk does not block.
+func (i *Image) FoundContext(_ context.Context) bool {
+	return i.Found()
+}
+
 func (i *Image) Valid() bool {
 	return i.Found()
 }
@@ -119,6 +140,13 @@ func (i *Image) GetAnnotateRefName() (string, error) {
 // GetLayer retrieves layer by diff id. Returns a reader of the uncompressed contents of the layer.
 // When the layers (notExistsLayer) came from a sparse image returns an empty reader
 func (i *Image) GetLayer(sha string) (io.ReadCloser, error) {
+	return i.GetLayerContext(context.Background(), sha)
+}
+
+func (i *Image) GetLayerContext(ctx context.Context, sha string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	layers, err := i.Image.Layers()
 	if err != nil {
 		return nil, err
@@ -312,6 +340,19 @@ func (i *Image) Rename(name string) {
 	i.path = name
 }
 
+// SetAnnotation sets an annotation on the image manifest, replacing any existing value for the key.
+// The `org.opencontainers.image.ref.name` annotation is saved on the image descriptor in the layout index instead.
+func (i *Image) SetAnnotation(key, value string) error {
+	if key == ImageRefNameKey {
+		return i.AnnotateRefName(value)
+	}
+	image, ok := mutate.Annotations(i.Image, map[string]string{key: value}).(v1.Image)
+	if !ok {
+		return fmt.Errorf("failed to set annotation %q for image at path %q", key, i.path)
+	}
+	return i.setUnderlyingImage(image)
+}
+
 func (i *Image) SetArchitecture(architecture string) error {
 	configFile, err := i.Image.ConfigFile()
 	if err != nil {
@@ -478,11 +519,104 @@ func (i *Image) AddLayerWithDiffIDAndHistory(path, diffID string, history v1.His
 }
 
 func (i *Image) Delete() error {
+	return i.DeleteContext(context.Background())
+}
+
+func (i *Image) DeleteContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	return os.RemoveAll(i.path)
 }
 
-func (i *Image) Rebase(s string, image imgutil.Image) error {
-	return errors.New("not yet implemented")
+// MutateConfigFile calls withFunc on a copy of the config file of the image, and sets the result as the config file.
+// If the mutation does not pass imgutil.ValidateConfigFileMutation, an error is returned and the image is left as is.
+func (i *Image) MutateConfigFile(withFunc func(c *v1.ConfigFile)) error {
+	before, err := i.Image.ConfigFile()
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return fmt.Errorf("missing config for image %q", i.path)
+	}
+	configFile := before.DeepCopy()
+	withFunc(configFile)
+	if err = imgutil.ValidateConfigFileMutation(before, configFile); err != nil {
+		return err
+	}
+	return i.mutateConfigFile(i.Image, configFile)
+}
+
+// Rebase replaces the layers of the image up to and including baseTopLayer with the layers of newBase,
+// which can be an image of any kind. Layers, history and annotations above the old base are preserved.
+func (i *Image) Rebase(baseTopLayer string, newBase imgutil.Image) error {
+	newBaseImage, err := imgutil.NewBaseImage(newBase)
+	if err != nil {
+		return err
+	}
+
+	// keep the media types of the original image unless others were requested
+	mediaTypes := i.requestedMediaTypes
+	if mediaTypes == imgutil.MissingTypes || mediaTypes == imgutil.DefaultTypes {
+		if mediaTypes, err = mediaTypesOf(i.Image); err != nil {
+			return err
+		}
+	}
+
+	annotations, err := i.manifestAnnotations()
+	if err != nil {
+		return err
+	}
+
+	newImage, err := mutate.Rebase(i.Image, imgutil.SubImage(i.Image, baseTopLayer), newBaseImage)
+	if err != nil {
+		return errors.Wrap(err, "rebase")
+	}
+
+	newImageConfig, err := newImage.ConfigFile()
+	if err != nil {
+		return err
+	}
+
+	newBaseConfig, err := newBaseImage.ConfigFile()
+	if err != nil {
+		return err
+	}
+
+	newImageConfig.Architecture = newBaseConfig.Architecture
+	newImageConfig.OS = newBaseConfig.OS
+	newImageConfig.OSVersion = newBaseConfig.OSVersion
+
+	newImage, err = mutate.ConfigFile(newImage, newImageConfig)
+	if err != nil {
+		return err
+	}
+
+	newImage, err = imgutil.OverrideMediaTypes(newImage, mediaTypes)
+	if err != nil {
+		return err
+	}
+	return i.setUnderlyingImage(imgutil.OverrideAnnotations(newImage, annotations))
+}
+
+// RebaseWithReport is like Rebase, but first validates the new base as requested and reports what the rebase changed.
+func (i *Image) RebaseWithReport(baseTopLayer string, newBase imgutil.Image, ops ...imgutil.RebaseOption) (imgutil.RebaseReport, error) {
+	return imgutil.RebaseWithReport(i, baseTopLayer, newBase, func() error {
+		return i.Rebase(baseTopLayer, newBase)
+	}, ops...)
+}
+
+func (i *Image) RemoveAnnotation(key string) error {
+	if key == ImageRefNameKey {
+		i.refName = ""
+		return nil
+	}
+	annotations, err := i.manifestAnnotations()
+	if err != nil {
+		return err
+	}
+	delete(annotations, key)
+	return i.setUnderlyingImage(imgutil.OverrideAnnotations(i.Image, annotations))
 }
 
 func (i *Image) RemoveLabel(key string) error {
@@ -527,7 +661,22 @@ func findLayerWithSha(layers []v1.Layer, diffID string) (v1.Layer, int, error) {
 			return layer, idx, nil
 		}
 	}
-	return nil, -1, fmt.Errorf("previous image did not have layer with diff id %q", diffID)
+	return nil, -1, imgutil.Errorf(imgutil.ErrLayerNotFound, "previous image did not have layer with diff id %q", diffID)
+}
+
+func (i *Image) manifestAnnotations() (map[string]string, error) {
+	manifest, err := i.Image.Manifest()
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting manifest for image at path %q", i.path)
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("missing manifest for image at path %q", i.path)
+	}
+	annotations := make(map[string]string, len(manifest.Annotations))
+	for k, v := range manifest.Annotations {
+		annotations[k] = v
+	}
+	return annotations, nil
 }
 
 // mutateConfig mutates the provided v1.Image to have the provided v1.Config,
@@ -551,3 +700,18 @@ func (i *Image) mutateConfigFile(base v1.Image, configFile *v1.ConfigFile) error
 	}
 	return i.setUnderlyingImage(image)
 }
+
+func mediaTypesOf(image v1.Image) (imgutil.MediaTypes, error) {
+	mediaType, err := image.MediaType()
+	if err != nil {
+		return imgutil.MissingTypes, err
+	}
+	switch mediaType {
+	case types.OCIManifestSchema1:
+		return imgutil.OCITypes, nil
+	case types.DockerManifestSchema2:
+		return imgutil.DockerTypes, nil
+	default:
+		return imgutil.DefaultTypes, nil
+	}
+}
@@ -1,13 +1,24 @@
 package layout_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 
 	"github.com/google/go-containerregistry/pkg/v1/remote"
@@ -16,7 +27,9 @@ import (
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 
+	"github.com/buildpacks/imgutil/fakes"
 	"github.com/buildpacks/imgutil/layout"
+	imgutilremote "github.com/buildpacks/imgutil/remote"
 
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
@@ -121,6 +134,26 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 				h.AssertError(t, err, "has no layers")
 			})
 
+			it("sets the variant and os features", func() {
+				img, err := layout.NewImage(
+					imagePath,
+					layout.WithDefaultPlatform(imgutil.Platform{
+						Architecture: "arm",
+						OS:           "linux",
+						Variant:      "v7",
+					}.WithOSFeatures("some-feature")),
+				)
+				h.AssertNil(t, err)
+				h.AssertNil(t, img.Save())
+
+				variant, err := img.Variant()
+				h.AssertNil(t, err)
+				h.AssertEq(t, variant, "v7")
+
+				_, configFile := h.ReadManifestAndConfigFile(t, imagePath)
+				h.AssertEq(t, configFile.OSFeatures, []string{"some-feature"})
+			})
+
 			it("sets all platform required fields for linux", func() {
 				img, err := layout.NewImage(
 					imagePath,
@@ -245,6 +278,87 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 				})
 			})
 
+			when("base image is a multi-platform index", func() {
+				var indexPath string
+
+				newPlatformImage := func(platform imgutil.Platform) *layout.Image {
+					img, err := layout.NewImage(filepath.Join(tmpDir, "platform-image"), layout.WithDefaultPlatform(platform))
+					h.AssertNil(t, err)
+					return img
+				}
+
+				it.Before(func() {
+					indexPath = filepath.Join(tmpDir, "multi-platform-index")
+					idx, err := layout.NewIndex(indexPath)
+					h.AssertNil(t, err)
+					h.AssertNil(t, idx.Add(newPlatformImage(imgutil.Platform{OS: "linux", Architecture: "amd64"})))
+					h.AssertNil(t, idx.Add(newPlatformImage(imgutil.Platform{OS: "linux", Architecture: "arm64"})))
+					h.AssertNil(t, idx.Save())
+				})
+
+				it("selects the image matching the platform", func() {
+					img, err := layout.NewImage(imagePath,
+						layout.FromBaseImagePath(indexPath),
+						layout.WithDefaultPlatform(imgutil.Platform{OS: "linux", Architecture: "arm64"}),
+					)
+					h.AssertNil(t, err)
+
+					arch, err := img.Architecture()
+					h.AssertNil(t, err)
+					h.AssertEq(t, arch, "arm64")
+				})
+
+				it("selects the image matching the platform from a nested index", func() {
+					base, err := layout.NewIndex(indexPath, layout.FromBaseIndexPath(indexPath))
+					h.AssertNil(t, err)
+					nestedIndex := mutate.AppendManifests(empty.Index, mutate.IndexAddendum{Add: base.UnderlyingIndex()})
+					nestedIndexPath := filepath.Join(tmpDir, "nested-index")
+					_, err = layout.Write(nestedIndexPath, nestedIndex)
+					h.AssertNil(t, err)
+
+					img, err := layout.NewImage(imagePath,
+						layout.FromBaseImagePath(nestedIndexPath),
+						layout.WithDefaultPlatform(imgutil.Platform{OS: "linux", Architecture: "arm64"}),
+					)
+					h.AssertNil(t, err)
+
+					arch, err := img.Architecture()
+					h.AssertNil(t, err)
+					h.AssertEq(t, arch, "arm64")
+				})
+
+				it("selects the image matching the platform variant", func() {
+					idx, err := layout.NewIndex(indexPath, layout.FromBaseIndexPath(indexPath))
+					h.AssertNil(t, err)
+					h.AssertNil(t, idx.Add(newPlatformImage(imgutil.Platform{OS: "linux", Architecture: "arm", Variant: "v6"})))
+					h.AssertNil(t, idx.Add(newPlatformImage(imgutil.Platform{OS: "linux", Architecture: "arm", Variant: "v7"})))
+					h.AssertNil(t, idx.Save())
+
+					img, err := layout.NewImage(imagePath,
+						layout.FromBaseImagePath(indexPath),
+						layout.WithDefaultPlatform(imgutil.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}),
+					)
+					h.AssertNil(t, err)
+
+					variant, err := img.Variant()
+					h.AssertNil(t, err)
+					h.AssertEq(t, variant, "v7")
+				})
+
+				it("returns an error listing the available platforms when none match", func() {
+					_, err := layout.NewImage(imagePath,
+						layout.FromBaseImagePath(indexPath),
+						layout.WithDefaultPlatform(imgutil.Platform{OS: "windows", Architecture: "amd64"}),
+					)
+					h.AssertError(t, err, "manifest matching platform windows/amd64 not found; available platforms: [linux/amd64, linux/arm64]")
+
+					var platformErr layout.PlatformNotFoundError
+					h.AssertEq(t, errors.As(err, &platformErr), true)
+					h.AssertEq(t, len(platformErr.Available), 2)
+					h.AssertEq(t, errors.Is(err, imgutil.ErrPlatformMismatch), true)
+				})
+			})
+
 			when("base image does not exist", func() {
 				it("returns an empty image", func() {
 					img, err := layout.NewImage(imagePath, layout.FromBaseImagePath("some-bad-repo-name"))
@@ -368,6 +482,69 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#MutateConfigFile", func() {
+		var image *layout.Image
+
+		it.Before(func() {
+			imagePath = filepath.Join(tmpDir, "mutate-config-file-image")
+			image, err = layout.NewImage(imagePath)
+			h.AssertNil(t, err)
+		})
+
+		it.After(func() {
+			os.RemoveAll(imagePath)
+		})
+
+		it("config file is saved on disk in OCI layout format", func() {
+			err = image.MutateConfigFile(func(c *v1.ConfigFile) {
+				c.Config.User = "some-user"
+				c.Config.ExposedPorts = map[string]struct{}{"8080/tcp": {}}
+				c.Config.StopSignal = "SIGKILL"
+			})
+			h.AssertNil(t, err)
+
+			err = image.Save()
+			h.AssertNil(t, err)
+
+			_, configFile := h.ReadManifestAndConfigFile(t, imagePath)
+			h.AssertEq(t, configFile.Config.User, "some-user")
+			h.AssertEq(t, configFile.Config.ExposedPorts, map[string]struct{}{"8080/tcp": {}})
+			h.AssertEq(t, configFile.Config.StopSignal, "SIGKILL")
+
+			// the config file of the loaded image is the same
+			imageLoaded, err := layout.NewImage(imagePath, layout.FromBaseImagePath(imagePath))
+			h.AssertNil(t, err)
+			loadedConfigFile, err := imageLoaded.ConfigFile()
+			h.AssertNil(t, err)
+			h.AssertEq(t, loadedConfigFile.Config.User, "some-user")
+		})
+
+		it("errors when the layers or the number of history entries are changed", func() {
+			layerPath, err := h.CreateSingleFileLayerTar("/some-file.txt", "some-content", "linux")
+			h.AssertNil(t, err)
+			defer os.Remove(layerPath)
+			h.AssertNil(t, image.AddLayer(layerPath))
+
+			err = image.MutateConfigFile(func(c *v1.ConfigFile) {
+				c.Config.User = "some-user"
+				c.RootFS.DiffIDs = c.RootFS.DiffIDs[:len(c.RootFS.DiffIDs)-1]
+			})
+			h.AssertEq(t, errors.Is(err, imgutil.ErrNotImplemented), true)
+
+			err = image.MutateConfigFile(func(c *v1.ConfigFile) {
+				c.Config.User = "some-user"
+				c.History = append(c.History, v1.History{CreatedBy: "some-history"})
+			})
+			h.AssertError(t, err, "invalid history")
+
+			// the image is left as is
+			configFile, err := image.ConfigFile()
+			h.AssertNil(t, err)
+			h.AssertEq(t, configFile.Config.User, "")
+			h.AssertEq(t, len(configFile.RootFS.DiffIDs), 1)
+		})
+	})
+
 	when("#EntryPoint", func() {
 		var image *layout.Image
 
@@ -725,6 +902,39 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 			})
 		})
 
+		when("#WithProgress", func() {
+			it.Before(func() {
+				imagePath = filepath.Join(tmpDir, "save-with-progress")
+			})
+
+			it("reports the progress of writing each layer", func() {
+				var updates []imgutil.Progress
+				image, err := layout.NewImage(
+					imagePath,
+					layout.WithProgress(func(p imgutil.Progress) { updates = append(updates, p) }),
+				)
+				h.AssertNil(t, err)
+
+				layerPath, diffID, _ := h.RandomLayer(t, tmpDir)
+				h.AssertNil(t, image.AddLayerWithDiffID(layerPath, diffID))
+
+				h.AssertNil(t, image.Save())
+
+				hash, err := v1.NewHash(diffID)
+				h.AssertNil(t, err)
+				layer, err := image.UnderlyingImage().LayerByDiffID(hash)
+				h.AssertNil(t, err)
+				digest, err := layer.Digest()
+				h.AssertNil(t, err)
+				size, err := layer.Size()
+				h.AssertNil(t, err)
+
+				h.AssertEq(t, len(updates) > 0, true)
+				last := updates[len(updates)-1]
+				h.AssertEq(t, last, imgutil.Progress{Digest: digest, Complete: size, Total: size, Phase: imgutil.PhaseWriting})
+			})
+		})
+
 		when("#FromBaseImagePath", func() {
 			it.Before(func() {
 				imagePath = filepath.Join(tmpDir, "save-from-base-image-path")
@@ -1002,6 +1212,73 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#SaveWithResult", func() {
+		var anotherPath string
+
+		it.Before(func() {
+			imagePath = filepath.Join(tmpDir, "save-with-result-image")
+			anotherPath = filepath.Join(tmpDir, "another-save-with-result-image")
+		})
+
+		it.After(func() {
+			os.RemoveAll(imagePath)
+			os.RemoveAll(anotherPath)
+		})
+
+		it("returns the manifest saved at each path", func() {
+			image, err := layout.NewImage(imagePath)
+			h.AssertNil(t, err)
+
+			results, err := image.SaveWithResult(anotherPath)
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(results), 2)
+
+			configName, err := image.UnderlyingImage().ConfigName()
+			h.AssertNil(t, err)
+			for idx, path := range []string{imagePath, anotherPath} {
+				index := h.ReadIndexManifest(t, path)
+				h.AssertEq(t, len(index.Manifests), 1)
+
+				h.AssertEq(t, results[idx].Name, path)
+				h.AssertEq(t, results[idx].Digest, index.Manifests[0].Digest)
+				h.AssertEq(t, results[idx].Size, index.Manifests[0].Size)
+				h.AssertEq(t, results[idx].MediaType, types.OCIManifestSchema1)
+				h.AssertEq(t, results[idx].ImageID, configName.String())
+				h.AssertNil(t, results[idx].Err)
+			}
+		})
+	})
+
+	when("#SaveContext", func() {
+		it.Before(func() {
+			imagePath = filepath.Join(tmpDir, "save-context-image")
+		})
+
+		it.After(func() {
+			os.RemoveAll(imagePath)
+		})
+
+		it("saves the image when the context is not done", func() {
+			image, err := layout.NewImage(imagePath)
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, image.SaveContext(context.Background()))
+			h.AssertTrue(t, image.Found)
+		})
+
+		it("returns the context error without writing the image when the context is done", func() {
+			image, err := layout.NewImage(imagePath)
+			h.AssertNil(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err = image.SaveContext(ctx)
+			h.AssertTrue(t, func() bool { return errors.Is(err, context.Canceled) })
+			h.AssertTrue(t, func() bool { return !image.Found() })
+		})
+	})
+
 	when("#Found", func() {
 		var image *layout.Image
 
@@ -1127,6 +1404,17 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 				return !image.Found()
 			})
 		})
+
+		when("the context is done", func() {
+			it("returns the context error and leaves the image on disk", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				err = image.DeleteContext(ctx)
+				h.AssertTrue(t, func() bool { return errors.Is(err, context.Canceled) })
+				h.AssertTrue(t, image.Found)
+			})
+		})
 	})
 
 	when("#Platform", func() {
@@ -1193,5 +1481,394 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 				h.AssertNil(t, err)
 			})
 		})
+
+		when("the image doesn't have the layer", func() {
+			it("returns an error matching imgutil.ErrLayerNotFound", func() {
+				image, err := layout.NewImage(imagePath, layout.FromBaseImagePath(sparseBaseImagePath))
+				h.AssertNil(t, err)
+
+				_, err = image.GetLayer("sha256:0000000000000000000000000000000000000000000000000000000000000000")
+				h.AssertError(t, err, "previous image did not have layer with diff id")
+				h.AssertEq(t, errors.Is(err, imgutil.ErrLayerNotFound), true)
+			})
+		})
+	})
+
+	when("#Rebase", func() {
+		var (
+			oldBasePath, newBasePath, oldTopLayerDiffID string
+			newBaseDiffIDs                              []v1.Hash
+		)
+
+		newBaseImage := func(path string, contents ...string) []v1.Hash {
+			image, err := layout.NewImage(path)
+			h.AssertNil(t, err)
+			for idx, txt := range contents {
+				layerPath, err := h.CreateSingleFileLayerTar(fmt.Sprintf("/base-%d.txt", idx), txt, "linux")
+				h.AssertNil(t, err)
+				defer os.Remove(layerPath)
+				h.AssertNil(t, image.AddLayer(layerPath))
+			}
+			h.AssertNil(t, image.SetOSVersion(path))
+			h.AssertNil(t, image.Save())
+			_, configFile := h.ReadManifestAndConfigFile(t, path)
+			return configFile.RootFS.DiffIDs
+		}
+
+		it.Before(func() {
+			imagePath = filepath.Join(tmpDir, "rebase-image")
+			oldBasePath = filepath.Join(tmpDir, "old-base")
+			newBasePath = filepath.Join(tmpDir, "new-base")
+
+			oldBaseDiffIDs := newBaseImage(oldBasePath, "old-base-1", "old-base-2")
+			oldTopLayerDiffID = oldBaseDiffIDs[len(oldBaseDiffIDs)-1].String()
+			newBaseDiffIDs = newBaseImage(newBasePath, "new-base-1", "new-base-2", "new-base-3")
+		})
+
+		it("switches the base and keeps the app layers, history and ref name", func() {
+			image, err := layout.NewImage(imagePath, layout.FromBaseImagePath(oldBasePath), layout.WithHistory())
+			h.AssertNil(t, err)
+			appLayerPath, err := h.CreateSingleFileLayerTar("/app.txt", "app", "linux")
+			h.AssertNil(t, err)
+			defer os.Remove(appLayerPath)
+			h.AssertNil(t, image.AddLayerWithDiffIDAndHistory(appLayerPath, "", v1.History{CreatedBy: "some-app-layer"}))
+			h.AssertNil(t, image.AnnotateRefName("my-tag"))
+
+			newBase, err := layout.NewImage(newBasePath, layout.FromBaseImagePath(newBasePath))
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, image.Rebase(oldTopLayerDiffID, newBase))
+			h.AssertNil(t, image.Save())
+
+			_, configFile := h.ReadManifestAndConfigFile(t, imagePath)
+			h.AssertEq(t, configFile.RootFS.DiffIDs[:len(newBaseDiffIDs)], newBaseDiffIDs)
+			h.AssertEq(t, len(configFile.RootFS.DiffIDs), len(newBaseDiffIDs)+1)
+			h.AssertEq(t, configFile.OSVersion, newBasePath)
+			h.AssertEq(t, configFile.History[len(configFile.History)-1].CreatedBy, "some-app-layer")
+
+			index := h.ReadIndexManifest(t, imagePath)
+			h.AssertEqAnnotation(t, index.Manifests[0], "org.opencontainers.image.ref.name", "my-tag")
+		})
+
+		it("rebases onto a new base from a registry", func() {
+			server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			defer server.Close()
+			host := strings.TrimPrefix(server.URL, "http://")
+			opts := []imgutilremote.ImageOption{imgutilremote.WithRegistryConfig(host, imgutilremote.RegistryConfig{PlainHTTP: true})}
+
+			remoteBase, err := imgutilremote.NewImage(host+"/new-base", authn.DefaultKeychain, append(opts,
+				imgutilremote.FromBaseImage(host+"/new-base"),
+			)...)
+			h.AssertNil(t, err)
+			for idx, txt := range []string{"remote-base-1", "remote-base-2"} {
+				layerPath, err := h.CreateSingleFileLayerTar(fmt.Sprintf("/remote-base-%d.txt", idx), txt, "linux")
+				h.AssertNil(t, err)
+				defer os.Remove(layerPath)
+				h.AssertNil(t, remoteBase.AddLayer(layerPath))
+			}
+			h.AssertNil(t, remoteBase.Save())
+			newBase, err := imgutilremote.NewImage(host+"/new-base", authn.DefaultKeychain, append(opts,
+				imgutilremote.FromBaseImage(host+"/new-base"),
+			)...)
+			h.AssertNil(t, err)
+			newBaseConfig, err := newBase.UnderlyingImage().ConfigFile()
+			h.AssertNil(t, err)
+
+			image, err := layout.NewImage(imagePath, layout.FromBaseImagePath(oldBasePath))
+			h.AssertNil(t, err)
+			h.AssertNil(t, image.Rebase(oldTopLayerDiffID, newBase))
+			h.AssertNil(t, image.Save())
+
+			_, configFile := h.ReadManifestAndConfigFile(t, imagePath)
+			h.AssertEq(t, configFile.RootFS.DiffIDs, newBaseConfig.RootFS.DiffIDs)
+			// the layers of the new base are fetched from the registry when the image is saved
+			h.AssertBlobsLen(t, imagePath, 4)
+		})
+
+		it("errors when the new base has no content", func() {
+			image, err := layout.NewImage(imagePath, layout.FromBaseImagePath(oldBasePath))
+			h.AssertNil(t, err)
+
+			err = image.Rebase(oldTopLayerDiffID, fakes.NewImage("some-image", "", nil))
+			h.AssertError(t, err, `missing new base image "some-image"`)
+		})
+
+		it("errors when the old base top layer is not found", func() {
+			image, err := layout.NewImage(imagePath, layout.FromBaseImagePath(oldBasePath))
+			h.AssertNil(t, err)
+			newBase, err := layout.NewImage(newBasePath, layout.FromBaseImagePath(newBasePath))
+			h.AssertNil(t, err)
+
+			err = image.Rebase(newBaseDiffIDs[0].String(), newBase)
+			h.AssertError(t, err, "could not find base layer in image")
+		})
+	})
+
+	when("#RebaseWithReport", func() {
+		var (
+			oldBasePath, newBasePath string
+			oldBaseDiffIDs           []v1.Hash
+			newBaseDiffIDs           []v1.Hash
+		)
+
+		it.Before(func() {
+			imagePath = filepath.Join(tmpDir, "rebase-report-image")
+			oldBasePath = filepath.Join(tmpDir, "old-base")
+			newBasePath = filepath.Join(tmpDir, "new-base")
+
+			for path, contents := range map[string][]string{
+				oldBasePath: {"old-base-1", "old-base-2"},
+				newBasePath: {"new-base-1", "new-base-2", "new-base-3"},
+			} {
+				image, err := layout.NewImage(path)
+				h.AssertNil(t, err)
+				for idx, txt := range contents {
+					layerPath, err := h.CreateSingleFileLayerTar(fmt.Sprintf("/base-%d.txt", idx), txt, "linux")
+					h.AssertNil(t, err)
+					defer os.Remove(layerPath)
+					h.AssertNil(t, image.AddLayer(layerPath))
+				}
+				h.AssertNil(t, image.SetLabel("io.buildpacks.stack.id", "some-stack"))
+				h.AssertNil(t, image.SetOSVersion(filepath.Base(path)))
+				h.AssertNil(t, image.Save())
+			}
+			_, configFile := h.ReadManifestAndConfigFile(t, oldBasePath)
+			oldBaseDiffIDs = configFile.RootFS.DiffIDs
+			_, configFile = h.ReadManifestAndConfigFile(t, newBasePath)
+			newBaseDiffIDs = configFile.RootFS.DiffIDs
+		})
+
+		it("reports the base layers and the config fields that changed", func() {
+			image, err := layout.NewImage(imagePath, layout.FromBaseImagePath(oldBasePath))
+			h.AssertNil(t, err)
+			appLayerPath, err := h.CreateSingleFileLayerTar("/app.txt", "app", "linux")
+			h.AssertNil(t, err)
+			defer os.Remove(appLayerPath)
+			h.AssertNil(t, image.AddLayer(appLayerPath))
+			newBase, err := layout.NewImage(newBasePath, layout.FromBaseImagePath(newBasePath))
+			h.AssertNil(t, err)
+
+			report, err := image.RebaseWithReport(
+				oldBaseDiffIDs[len(oldBaseDiffIDs)-1].String(),
+				newBase,
+				imgutil.WithPlatformValidation(),
+				imgutil.WithLabelValidation("io.buildpacks.stack.id"),
+			)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, report.RemovedBaseLayers, oldBaseDiffIDs)
+			h.AssertEq(t, report.AddedBaseLayers, newBaseDiffIDs)
+			h.AssertEq(t, report.ChangedConfig, []imgutil.ConfigChange{{Field: "OSVersion", Old: "old-base", New: "new-base"}})
+		})
+
+		it("errors when the new base is for another platform", func() {
+			image, err := layout.NewImage(imagePath, layout.FromBaseImagePath(oldBasePath))
+			h.AssertNil(t, err)
+			newBase, err := layout.NewImage(newBasePath, layout.FromBaseImagePath(newBasePath))
+			h.AssertNil(t, err)
+			h.AssertNil(t, newBase.SetArchitecture("arm64"))
+
+			_, err = image.RebaseWithReport(oldBaseDiffIDs[len(oldBaseDiffIDs)-1].String(), newBase, imgutil.WithPlatformValidation())
+			h.AssertError(t, err, `new base image "`+newBasePath+`" has architecture "arm64", expected "amd64"`)
+			h.AssertEq(t, errors.Is(err, imgutil.ErrIncompatibleBase), true)
+
+			// the image is left as is
+			topLayer, err := image.TopLayer()
+			h.AssertNil(t, err)
+			h.AssertEq(t, topLayer, oldBaseDiffIDs[len(oldBaseDiffIDs)-1].String())
+		})
+
+		it("requires Windows bases to have the same OS build", func() {
+			image, err := layout.NewImage(imagePath, layout.FromBaseImagePath(oldBasePath))
+			h.AssertNil(t, err)
+			h.AssertNil(t, image.SetOS("windows"))
+			h.AssertNil(t, image.SetOSVersion("10.0.17763.1234"))
+			newBase, err := layout.NewImage(newBasePath, layout.FromBaseImagePath(newBasePath))
+			h.AssertNil(t, err)
+			h.AssertNil(t, newBase.SetOS("windows"))
+			h.AssertNil(t, newBase.SetOSVersion("10.0.20348.1"))
+
+			_, err = image.RebaseWithReport(oldBaseDiffIDs[len(oldBaseDiffIDs)-1].String(), newBase, imgutil.WithPlatformValidation())
+			h.AssertEq(t, errors.Is(err, imgutil.ErrIncompatibleBase), true)
+
+			h.AssertNil(t, newBase.SetOSVersion("10.0.17763.5678"))
+			report, err := image.RebaseWithReport(oldBaseDiffIDs[len(oldBaseDiffIDs)-1].String(), newBase, imgutil.WithPlatformValidation())
+			h.AssertNil(t, err)
+			h.AssertEq(t, report.ChangedConfig, []imgutil.ConfigChange{{Field: "OSVersion", Old: "10.0.17763.1234", New: "10.0.17763.5678"}})
+		})
+
+		it("errors when a label of the new base differs", func() {
+			image, err := layout.NewImage(imagePath, layout.FromBaseImagePath(oldBasePath))
+			h.AssertNil(t, err)
+			newBase, err := layout.NewImage(newBasePath, layout.FromBaseImagePath(newBasePath))
+			h.AssertNil(t, err)
+			h.AssertNil(t, newBase.SetLabel("io.buildpacks.stack.id", "other-stack"))
+
+			_, err = image.RebaseWithReport(
+				oldBaseDiffIDs[len(oldBaseDiffIDs)-1].String(),
+				newBase,
+				imgutil.WithLabelValidation("io.buildpacks.stack.id"),
+			)
+			h.AssertError(t, err, `has label "io.buildpacks.stack.id" set to "other-stack", expected "some-stack"`)
+			h.AssertEq(t, errors.Is(err, imgutil.ErrIncompatibleBase), true)
+		})
+	})
+
+	when("#SaveFile", func() {
+		it.Before(func() {
+			imagePath = filepath.Join(tmpDir, "save-file-image")
+		})
+
+		it("writes a docker archive tagged with the ref name", func() {
+			image, err := layout.NewImage(imagePath)
+			h.AssertNil(t, err)
+			layerPath, _, _ := h.RandomLayer(t, tmpDir)
+			h.AssertNil(t, image.AddLayer(layerPath))
+			h.AssertNil(t, image.AnnotateRefName("some-registry.io/some-repo:some-tag"))
+
+			path, err := image.SaveFile()
+			h.AssertNil(t, err)
+			defer os.Remove(path)
+
+			tag, err := name.NewTag("some-registry.io/some-repo:some-tag")
+			h.AssertNil(t, err)
+			saved, err := tarball.ImageFromPath(path, &tag)
+			h.AssertNil(t, err)
+
+			savedConfig, err := saved.ConfigName()
+			h.AssertNil(t, err)
+			expectedConfig, err := image.UnderlyingImage().ConfigName()
+			h.AssertNil(t, err)
+			h.AssertEq(t, savedConfig, expectedConfig)
+		})
+
+		it("writes an untagged docker archive when there is no ref name", func() {
+			image, err := layout.NewImage(imagePath)
+			h.AssertNil(t, err)
+
+			path, err := image.SaveFile()
+			h.AssertNil(t, err)
+			defer os.Remove(path)
+
+			saved, err := tarball.ImageFromPath(path, nil)
+			h.AssertNil(t, err)
+			_, err = saved.ConfigFile()
+			h.AssertNil(t, err)
+		})
+
+		it("errors for images with missing layers", func() {
+			image, err := layout.NewImage(imagePath, layout.FromBaseImagePath(sparseBaseImagePath))
+			h.AssertNil(t, err)
+
+			_, err = image.SaveFile()
+			h.AssertError(t, err, "is missing from image at path")
+		})
+	})
+
+	when("#SetAnnotation", func() {
+		it.Before(func() {
+			imagePath = filepath.Join(tmpDir, "annotated-image")
+		})
+
+		it("saves annotations on the manifest and the ref name on the index", func() {
+			image, err := layout.NewImage(imagePath)
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, image.SetAnnotation("org.opencontainers.image.source", "https://example.com/some-repo"))
+			h.AssertNil(t, image.SetAnnotation(layout.ImageRefNameKey, "my-tag"))
+			h.AssertNil(t, image.Save())
+
+			annotations, err := image.Annotations()
+			h.AssertNil(t, err)
+			h.AssertEq(t, annotations, map[string]string{
+				"org.opencontainers.image.source": "https://example.com/some-repo",
+				layout.ImageRefNameKey:            "my-tag",
+			})
+
+			manifest, _ := h.ReadManifestAndConfigFile(t, imagePath)
+			h.AssertEq(t, manifest.Annotations, map[string]string{"org.opencontainers.image.source": "https://example.com/some-repo"})
+			index := h.ReadIndexManifest(t, imagePath)
+			h.AssertEqAnnotation(t, index.Manifests[0], layout.ImageRefNameKey, "my-tag")
+		})
+	})
+
+	when("#RemoveAnnotation", func() {
+		it.Before(func() {
+			imagePath = filepath.Join(tmpDir, "annotated-image")
+		})
+
+		it("removes annotations from the manifest", func() {
+			image, err := layout.NewImage(imagePath)
+			h.AssertNil(t, err)
+			h.AssertNil(t, image.SetAnnotation("some-key", "some-value"))
+			h.AssertNil(t, image.SetAnnotation("some-other-key", "some-other-value"))
+
+			h.AssertNil(t, image.RemoveAnnotation("some-key"))
+			h.AssertNil(t, image.Save())
+
+			manifest, _ := h.ReadManifestAndConfigFile(t, imagePath)
+			h.AssertEq(t, manifest.Annotations, map[string]string{"some-other-key": "some-other-value"})
+		})
+	})
+
+	when("#Attach", func() {
+		it.Before(func() {
+			imagePath = filepath.Join(tmpDir, "attached-image")
+		})
+
+		it("adds artifacts to the layout and lists them as referrers of the image", func() {
+			image, err := layout.NewImage(imagePath)
+			h.AssertNil(t, err)
+			h.AssertNil(t, image.Save())
+
+			sbom, err := image.Attach(imgutil.Artifact{
+				ArtifactType: "application/spdx+json",
+				Blobs:        []imgutil.ArtifactBlob{{MediaType: "application/spdx+json", Data: []byte(`{"spdxVersion":"SPDX-2.3"}`)}},
+			})
+			h.AssertNil(t, err)
+			signature, err := image.Attach(imgutil.Artifact{
+				ArtifactType: "application/vnd.dev.cosign.artifact.sig.v1+json",
+				Annotations:  map[string]string{"some-key": "some-value"},
+			})
+			h.AssertNil(t, err)
+
+			index := h.ReadIndexManifest(t, imagePath)
+			h.AssertEq(t, len(index.Manifests), 3)
+
+			referrers, err := image.Referrers("")
+			h.AssertNil(t, err)
+			h.AssertEq(t, referrers, []v1.Descriptor{sbom, signature})
+
+			referrers, err = image.Referrers("application/spdx+json")
+			h.AssertNil(t, err)
+			h.AssertEq(t, referrers, []v1.Descriptor{sbom})
+		})
+
+		it("keeps the artifacts when the image is saved again", func() {
+			image, err := layout.NewImage(imagePath)
+			h.AssertNil(t, err)
+			h.AssertNil(t, image.Save())
+
+			sbom, err := image.Attach(imgutil.Artifact{ArtifactType: "application/spdx+json"})
+			h.AssertNil(t, err)
+			h.AssertNil(t, image.Save())
+
+			referrers, err := image.Referrers("")
+			h.AssertNil(t, err)
+			h.AssertEq(t, referrers, []v1.Descriptor{sbom})
+
+			// the artifacts of the previous image are dropped when the image is changed
+			h.AssertNil(t, image.SetLabel("some-key", "some-value"))
+			h.AssertNil(t, image.Save())
+			h.AssertEq(t, len(h.ReadIndexManifest(t, imagePath).Manifests), 1)
+		})
+
+		it("returns an error when the image was not saved", func() {
+			image, err := layout.NewImage(imagePath)
+			h.AssertNil(t, err)
+
+			_, err = image.Attach(imgutil.Artifact{ArtifactType: "application/spdx+json"})
+			h.AssertError(t, err, "opening layout for image")
+		})
 	})
 }
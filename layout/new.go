@@ -2,6 +2,7 @@ package layout
 
 import (
 	"fmt"
+	"strings"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/empty"
@@ -34,6 +35,7 @@ func NewImage(path string, ops ...ImageOption) (*Image, error) {
 		Image:       image,
 		path:        path,
 		withHistory: imageOpts.withHistory,
+		progress:    imageOpts.progress,
 	}
 
 	if imageOpts.prevImagePath != "" {
@@ -84,6 +86,8 @@ func emptyImage(platform imgutil.Platform) (v1.Image, error) {
 		History:      []v1.History{},
 		OS:           platform.OS,
 		OSVersion:    platform.OSVersion,
+		Variant:      platform.Variant,
+		OSFeatures:   platform.OSFeatures(),
 		RootFS: v1.RootFS{
 			Type:    "layers",
 			DiffIDs: []v1.Hash{},
@@ -161,7 +165,8 @@ func newV1Image(path string, platform imgutil.Platform, withHistory bool) (v1.Im
 }
 
 // imageFromIndex creates a v1.Image from the given Image Index, selecting the image manifest
-// that matches the given OS and architecture.
+// that matches the given platform. Nested indexes are searched as well.
+// When the index contains a single image manifest, it is returned regardless of the platform.
 func imageFromIndex(index v1.ImageIndex, platform imgutil.Platform) (v1.Image, error) {
 	indexManifest, err := index.IndexManifest()
 	if err != nil {
@@ -172,26 +177,96 @@ func imageFromIndex(index v1.ImageIndex, platform imgutil.Platform) (v1.Image, e
 		return nil, errors.New("no underlyingImage indexManifest found")
 	}
 
-	manifest := indexManifest.Manifests[0]
-	if len(indexManifest.Manifests) > 1 {
-		// Find based on platform (os/arch)
-		for _, m := range indexManifest.Manifests {
-			if m.Platform.OS == platform.OS && m.Platform.Architecture == platform.OS {
-				manifest = m
-				break
-			}
-		}
-		return nil, fmt.Errorf("manifest matching platform %v not found", platform)
+	if len(indexManifest.Manifests) == 1 && !indexManifest.Manifests[0].MediaType.IsIndex() {
+		return index.Image(indexManifest.Manifests[0].Digest)
 	}
 
-	image, err := index.Image(manifest.Digest)
+	var available []v1.Platform
+	image, err := findImageForPlatform(index, v1PlatformFrom(platform), &available)
 	if err != nil {
 		return nil, err
 	}
-
+	if image == nil {
+		return nil, PlatformNotFoundError{Platform: platform, Available: available}
+	}
 	return image, nil
 }
 
+// findImageForPlatform returns the first image manifest in the index that satisfies the given platform, or nil if there is none.
+// The platform of every image manifest that is visited is appended to available.
+func findImageForPlatform(index v1.ImageIndex, platform v1.Platform, available *[]v1.Platform) (v1.Image, error) {
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, desc := range indexManifest.Manifests {
+		switch {
+		case desc.MediaType.IsIndex():
+			nestedIndex, err := index.ImageIndex(desc.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("reading nested index %s: %w", desc.Digest, err)
+			}
+			image, err := findImageForPlatform(nestedIndex, platform, available)
+			if err != nil || image != nil {
+				return image, err
+			}
+		case desc.MediaType.IsImage():
+			descPlatform := desc.Platform
+			if descPlatform == nil {
+				// the descriptor doesn't declare a platform, so read it from the image config
+				image, err := index.Image(desc.Digest)
+				if err != nil {
+					return nil, err
+				}
+				configFile, err := image.ConfigFile()
+				if err != nil {
+					return nil, fmt.Errorf("reading config file of image %s: %w", desc.Digest, err)
+				}
+				descPlatform = configFile.Platform()
+			}
+			if descPlatform == nil {
+				continue
+			}
+			*available = append(*available, *descPlatform)
+			if descPlatform.Satisfies(platform) {
+				return index.Image(desc.Digest)
+			}
+		}
+	}
+	return nil, nil
+}
+
+func v1PlatformFrom(platform imgutil.Platform) v1.Platform {
+	return v1.Platform{
+		Architecture: platform.Architecture,
+		OS:           platform.OS,
+		OSVersion:    platform.OSVersion,
+		Variant:      platform.Variant,
+		OSFeatures:   platform.OSFeatures(),
+	}
+}
+
+// PlatformNotFoundError is returned when an image index doesn't contain an image manifest for the requested platform.
+type PlatformNotFoundError struct {
+	Platform  imgutil.Platform
+	Available []v1.Platform
+}
+
+func (e PlatformNotFoundError) Error() string {
+	available := make([]string, len(e.Available))
+	for idx, platform := range e.Available {
+		available[idx] = platform.String()
+	}
+	return fmt.Sprintf("manifest matching platform %s not found; available platforms: [%s]",
+		v1PlatformFrom(e.Platform).String(), strings.Join(available, ", "))
+}
+
+// Is makes PlatformNotFoundError match imgutil.ErrPlatformMismatch.
+func (e PlatformNotFoundError) Is(target error) bool {
+	return target == imgutil.ErrPlatformMismatch
+}
+
 func processBaseImageOption(ri *Image, baseImagePath string, platform imgutil.Platform) error {
 	baseImage, err := newV1Image(baseImagePath, platform, ri.withHistory)
 	if err != nil {
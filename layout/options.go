@@ -18,6 +18,7 @@ type options struct {
 	withHistory   bool
 	createdAt     time.Time
 	mediaTypes    imgutil.MediaTypes
+	progress      imgutil.ProgressHandler
 }
 
 // FromBaseImage loads the given image as the config and layers for the new image.
@@ -47,7 +48,7 @@ func WithCreatedAt(createdAt time.Time) ImageOption {
 	}
 }
 
-// WithDefaultPlatform provides Architecture/OS/OSVersion defaults for the new image.
+// WithDefaultPlatform provides Architecture/OS/OSVersion/Variant/OSFeatures defaults for the new image.
 // Defaults for a new image are ignored when FromBaseImage returns an image.
 // FromBaseImage and WithPreviousImage will use the platform to choose an image from a manifest list.
 func WithDefaultPlatform(platform imgutil.Platform) ImageOption {
@@ -84,3 +85,37 @@ func WithPreviousImage(path string) ImageOption {
 		return nil
 	}
 }
+
+// WithProgress registers a handler that is called with the progress of each layer
+// while the image is written by Save or SaveFile.
+func WithProgress(handler imgutil.ProgressHandler) ImageOption {
+	return func(opts *options) error {
+		opts.progress = handler
+		return nil
+	}
+}
+
+type IndexOption func(*indexOptions) error
+
+type indexOptions struct {
+	baseIndexPath string
+	mediaTypes    imgutil.MediaTypes
+}
+
+// FromBaseIndexPath (layout only) loads an existing index as the manifests for the new index.
+// Ignored if index is not found.
+func FromBaseIndexPath(path string) IndexOption {
+	return func(opts *indexOptions) error {
+		opts.baseIndexPath = path
+		return nil
+	}
+}
+
+// WithIndexMediaTypes lets a caller set the desired media type for the index manifest,
+// to be either an OCI image index or a Docker manifest list.
+func WithIndexMediaTypes(requested imgutil.MediaTypes) IndexOption {
+	return func(opts *indexOptions) error {
+		opts.mediaTypes = requested
+		return nil
+	}
+}
@@ -0,0 +1,141 @@
+package layout
+
+import (
+	"bytes"
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/imgutil"
+)
+
+// Attach writes the artifact to the layout the image is saved in, with the image as its subject,
+// and returns the descriptor that lists the artifact among the referrers of the image.
+// The image must be saved first, as the artifact manifest is added to the index of the layout.
+// Saving the image again keeps the artifacts attached to it, unless the image was changed in the meantime.
+func (i *Image) Attach(artifact imgutil.Artifact) (v1.Descriptor, error) {
+	subject, err := partial.Descriptor(i.Image)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	raw, blobs, err := imgutil.ArtifactManifest(artifact, *subject)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	desc, err := imgutil.ReferrerDescriptor(raw)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+
+	layoutPath, err := FromPath(i.path)
+	if err != nil {
+		return v1.Descriptor{}, errors.Wrapf(err, "opening layout for image at path %q", i.path)
+	}
+	for _, blob := range blobs {
+		if err := layoutPath.writeLayer(blob); err != nil {
+			return v1.Descriptor{}, errors.Wrap(err, "writing artifact blob")
+		}
+	}
+	if err := layoutPath.WriteBlob(desc.Digest, io.NopCloser(bytes.NewReader(raw))); err != nil {
+		return v1.Descriptor{}, errors.Wrap(err, "writing artifact manifest")
+	}
+	if err := layoutPath.AppendDescriptor(desc); err != nil {
+		return v1.Descriptor{}, errors.Wrap(err, "adding artifact manifest to index")
+	}
+	return desc, nil
+}
+
+// Referrers returns the descriptors of the artifacts in the layout the image is saved in whose subject is the image,
+// and that have the given artifact type, or all of them if artifactType is empty.
+func (i *Image) Referrers(artifactType string) ([]v1.Descriptor, error) {
+	digest, err := i.Image.Digest()
+	if err != nil {
+		return nil, err
+	}
+	layoutPath, err := FromPath(i.path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening layout for image at path %q", i.path)
+	}
+	_, manifests, err := attachedArtifacts(layoutPath, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	var referrers []v1.Descriptor
+	for _, raw := range manifests {
+		referrer, err := imgutil.ReferrerDescriptor(raw)
+		if err != nil {
+			return nil, err
+		}
+		referrers = append(referrers, referrer)
+	}
+	return imgutil.FilterReferrers(referrers, artifactType), nil
+}
+
+// attachedArtifacts returns the descriptors in the index of the layout of the artifacts whose subject is the image
+// with the given digest, along with their manifests.
+func attachedArtifacts(layoutPath Path, digest v1.Hash) ([]v1.Descriptor, [][]byte, error) {
+	index, err := layoutPath.ImageIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		descs     []v1.Descriptor
+		manifests [][]byte
+	)
+	for _, desc := range indexManifest.Manifests {
+		if !desc.MediaType.IsImage() {
+			continue
+		}
+		raw, err := layoutPath.Bytes(desc.Digest)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "reading manifest %s", desc.Digest)
+		}
+		subject, err := imgutil.Subject(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		if subject == nil || subject.Digest != digest {
+			continue
+		}
+		descs = append(descs, desc)
+		manifests = append(manifests, raw)
+	}
+	return descs, manifests, nil
+}
+
+// artifactsAttachedAt returns the descriptors of the artifacts whose subject is the image with the given digest
+// in the layout at path, if there is one.
+func artifactsAttachedAt(path string, digest v1.Hash) ([]v1.Descriptor, error) {
+	if !ImageExists(path) {
+		return nil, nil
+	}
+	layoutPath, err := FromPath(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening layout at path %q", path)
+	}
+	descs, _, err := attachedArtifacts(layoutPath, digest)
+	return descs, err
+}
+
+// ReadArtifact returns the artifact with the given descriptor, as returned by Attach or Referrers.
+func (i *Image) ReadArtifact(desc v1.Descriptor) (imgutil.Artifact, error) {
+	layoutPath, err := FromPath(i.path)
+	if err != nil {
+		return imgutil.Artifact{}, errors.Wrapf(err, "opening layout for image at path %q", i.path)
+	}
+	raw, err := layoutPath.Bytes(desc.Digest)
+	if err != nil {
+		return imgutil.Artifact{}, errors.Wrapf(err, "reading manifest %s", desc.Digest)
+	}
+	return imgutil.ParseArtifact(raw, func(blob v1.Descriptor) ([]byte, error) {
+		return layoutPath.Bytes(blob.Digest)
+	})
+}
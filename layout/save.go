@@ -1,6 +1,7 @@
 package layout
 
 import (
+	"context"
 	"fmt"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -12,11 +13,86 @@ import (
 )
 
 func (i *Image) Save(additionalNames ...string) error {
-	return i.SaveAs(i.Name(), additionalNames...)
+	return i.SaveAsContext(context.Background(), i.Name(), additionalNames...)
+}
+
+func (i *Image) SaveContext(ctx context.Context, additionalNames ...string) error {
+	return i.SaveAsContext(ctx, i.Name(), additionalNames...)
 }
 
 // SaveAs ignores the image `Name()` method and saves the image according to name & additional names provided to this method
 func (i *Image) SaveAs(name string, additionalNames ...string) error {
+	return i.SaveAsContext(context.Background(), name, additionalNames...)
+}
+
+// SaveAsContext is like SaveAs, but stops before writing the next path once ctx is done.
+func (i *Image) SaveAsContext(ctx context.Context, name string, additionalNames ...string) error {
+	_, err := i.saveAs(ctx, name, additionalNames...)
+	return err
+}
+
+func (i *Image) SaveWithResult(additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return i.saveAs(context.Background(), i.Name(), additionalNames...)
+}
+
+func (i *Image) SaveAsWithResult(name string, additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return i.saveAs(context.Background(), name, additionalNames...)
+}
+
+func (i *Image) saveAs(ctx context.Context, name string, additionalNames ...string) ([]imgutil.SaveResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := i.prepareForSave(); err != nil {
+		return nil, err
+	}
+	return i.write(ctx, append([]string{name}, additionalNames...))
+}
+
+// write writes the image to each of the given paths as is.
+func (i *Image) write(ctx context.Context, pathsToSave []string) ([]imgutil.SaveResult, error) {
+	saved, err := imgutil.NewSaveResult("", i.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []imgutil.SaveResult
+	annotations := ImageRefAnnotation(i.refName)
+	for _, path := range pathsToSave {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// the artifacts attached to the image are added back to the index, which is written anew
+		attached, err := artifactsAttachedAt(path, saved.Digest)
+		if err != nil {
+			return nil, err
+		}
+
+		// initialize image path
+		layoutPath, err := Write(path, empty.Index)
+		if err != nil {
+			return nil, err
+		}
+
+		result := saved
+		result.Name = path
+		err = layoutPath.AppendImage(imgutil.ProgressReportingImage(i.Image, imgutil.PhaseWriting, i.progress), WithAnnotations(annotations))
+		for _, desc := range attached {
+			if err == nil {
+				err = layoutPath.AppendDescriptor(desc)
+			}
+		}
+		if err != nil {
+			result = imgutil.SaveResult{Name: path, Err: err}
+		}
+		results = append(results, result)
+	}
+
+	return results, imgutil.SaveErrorFrom(results)
+}
+
+// prepareForSave normalizes the creation time and history of the image before it is written out.
+func (i *Image) prepareForSave() error {
 	err := i.mutateCreatedAt(i.Image, v1.Time{Time: i.createdAt})
 	if err != nil {
 		return errors.Wrap(err, "set creation time")
@@ -50,27 +126,6 @@ func (i *Image) SaveAs(name string, additionalNames ...string) error {
 	if err != nil {
 		return errors.Wrap(err, "zeroing history")
 	}
-
-	var diagnostics []imgutil.SaveDiagnostic
-	annotations := ImageRefAnnotation(i.refName)
-	pathsToSave := append([]string{name}, additionalNames...)
-	for _, path := range pathsToSave {
-		// initialize image path
-		path, err := Write(path, empty.Index)
-		if err != nil {
-			return err
-		}
-
-		err = path.AppendImage(i.Image, WithAnnotations(annotations))
-		if err != nil {
-			diagnostics = append(diagnostics, imgutil.SaveDiagnostic{ImageName: i.Name(), Cause: err})
-		}
-	}
-
-	if len(diagnostics) > 0 {
-		return imgutil.SaveError{Errors: diagnostics}
-	}
-
 	return nil
 }
 
@@ -1,8 +1,72 @@
 package layout
 
-import "errors"
+import (
+	"fmt"
+	"os"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/buildpacks/imgutil"
+)
+
+// SaveFile saves the image as a docker archive and provides the filesystem location.
+// The archive is tagged with the `org.opencontainers.image.ref.name` annotation when it is a valid image name;
+// otherwise the image is saved untagged. Every layer must be present in the layout, so sparse images cannot be saved.
 func (i *Image) SaveFile() (string, error) {
-	// TODO issue https://github.com/buildpacks/imgutil/issues/170
-	return "", errors.New("not yet implemented")
+	if err := i.prepareForSave(); err != nil {
+		return "", err
+	}
+	return i.saveFile()
+}
+
+// saveFile saves the image as is as a docker archive.
+func (i *Image) saveFile() (string, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return "", fmt.Errorf("getting layers: %w", err)
+	}
+	for _, layer := range layers {
+		if l, ok := layer.(*notExistsLayer); ok {
+			return "", imgutil.Errorf(imgutil.ErrLayerNotFound, "layer with diff id %q is missing from image at path %q", l.diffID.String(), i.path)
+		}
+	}
+
+	ref, err := i.archiveReference()
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", "imgutil.layout.image.export.*.tar")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		f.Close()
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err = tarball.Write(ref, imgutil.ProgressReportingImage(i.Image, imgutil.PhaseWriting, i.progress), f); err != nil {
+		return "", fmt.Errorf("writing image at path %q to file: %w", i.path, err)
+	}
+	return f.Name(), nil
 }
+
+// archiveReference returns the tag to record in the archive, or a digest reference when the image is untagged.
+func (i *Image) archiveReference() (name.Reference, error) {
+	if i.refName != "" {
+		if tag, err := name.NewTag(i.refName, name.WeakValidation); err == nil {
+			return tag, nil
+		}
+	}
+	digest, err := i.Image.Digest()
+	if err != nil {
+		return nil, fmt.Errorf("getting digest: %w", err)
+	}
+	return name.NewDigest(untaggedRepository + "@" + digest.String())
+}
+
+// untaggedRepository names the repository of untagged images in a docker archive; it is never written to the archive.
+const untaggedRepository = "imgutil"
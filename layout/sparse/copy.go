@@ -0,0 +1,22 @@
+package sparse
+
+import (
+	"context"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/layout"
+)
+
+var _ imgutil.CopyTarget = &Image{}
+
+// SaveCopy writes image as is to the path of the image, without its layers, and makes it the content of the image.
+func (i *Image) SaveCopy(ctx context.Context, image v1.Image) (imgutil.SaveResult, error) {
+	i.Image.Image = &layout.Image{Image: image}
+	results, err := i.saveAs(ctx, i.Name())
+	if err != nil {
+		return imgutil.SaveResult{}, err
+	}
+	return results[0], nil
+}
@@ -1,6 +1,8 @@
 package sparse
 
 import (
+	"context"
+
 	"github.com/google/go-containerregistry/pkg/v1/empty"
 
 	"github.com/buildpacks/imgutil"
@@ -8,31 +10,59 @@ import (
 )
 
 func (i *Image) Save(additionalNames ...string) error {
-	return i.SaveAs(i.Name(), additionalNames...)
+	return i.SaveAsContext(context.Background(), i.Name(), additionalNames...)
+}
+
+func (i *Image) SaveContext(ctx context.Context, additionalNames ...string) error {
+	return i.SaveAsContext(ctx, i.Name(), additionalNames...)
 }
 
 func (i *Image) SaveAs(name string, additionalNames ...string) error {
-	var diagnostics []imgutil.SaveDiagnostic
+	return i.SaveAsContext(context.Background(), name, additionalNames...)
+}
+
+func (i *Image) SaveAsContext(ctx context.Context, name string, additionalNames ...string) error {
+	_, err := i.saveAs(ctx, name, additionalNames...)
+	return err
+}
+
+func (i *Image) SaveWithResult(additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return i.saveAs(context.Background(), i.Name(), additionalNames...)
+}
+
+func (i *Image) SaveAsWithResult(name string, additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return i.saveAs(context.Background(), name, additionalNames...)
+}
+
+func (i *Image) saveAs(ctx context.Context, name string, additionalNames ...string) ([]imgutil.SaveResult, error) {
+	var results []imgutil.SaveResult
 
 	refName, _ := i.Image.GetAnnotateRefName()
 	annotations := layout.ImageRefAnnotation(refName)
 
+	saved, err := imgutil.NewSaveResult("", i)
+	if err != nil {
+		return nil, err
+	}
+
 	pathsToSave := append([]string{name}, additionalNames...)
 	for _, path := range pathsToSave {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		layoutPath, err := layout.Write(path, empty.Index)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
+		result := saved
+		result.Name = path
 		err = layoutPath.AppendImage(i, layout.WithoutLayers(), layout.WithAnnotations(annotations))
 		if err != nil {
-			diagnostics = append(diagnostics, imgutil.SaveDiagnostic{ImageName: name, Cause: err})
+			result = imgutil.SaveResult{Name: path, Err: err}
 		}
+		results = append(results, result)
 	}
 
-	if len(diagnostics) > 0 {
-		return imgutil.SaveError{Errors: diagnostics}
-	}
-
-	return nil
+	return results, imgutil.SaveErrorFrom(results)
 }
@@ -0,0 +1,146 @@
+package layout
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/buildpacks/imgutil"
+)
+
+// Store provides methods for interacting with OCI layouts on disk
+// in order to save, delete, and report the presence of images.
+// Images are identified by the path of their layout, optionally followed by "@" and the digest of the image.
+type Store struct {
+	// optional
+	progress imgutil.ProgressHandler
+}
+
+var _ imgutil.ContextImageStore = &Store{}
+
+// NewStore returns a store that writes images to layouts on disk.
+// The progress handler is set from ops; other options are ignored.
+func NewStore(ops ...ImageOption) (*Store, error) {
+	imageOpts := &options{}
+	for _, op := range ops {
+		if err := op(imageOpts); err != nil {
+			return nil, err
+		}
+	}
+	return &Store{progress: imageOpts.progress}, nil
+}
+
+// images
+
+func (s *Store) Contains(identifier string) bool {
+	return ImageExists(pathOf(identifier))
+}
+
+// ContainsContext is the same as Contains, as checking for the image on disk does not block.
+func (s *Store) ContainsContext(_ context.Context, identifier string) bool {
+	return s.Contains(identifier)
+}
+
+func (s *Store) Delete(identifier string) error {
+	return os.RemoveAll(pathOf(identifier))
+}
+
+func (s *Store) DeleteContext(ctx context.Context, identifier string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Delete(identifier)
+}
+
+// Save writes the image to the layout at each path, and returns the identifier of the image in the first layout,
+// i.e. its path followed by "@" and the digest of the image.
+func (s *Store) Save(image imgutil.IdentifiableV1Image, withName string, withAdditionalNames ...string) (string, error) {
+	results, err := s.SaveContext(context.Background(), image, withName, withAdditionalNames...)
+	if err != nil {
+		return "", err
+	}
+	identifier, err := newLayoutIdentifier(pathOf(withName), results[0].Digest)
+	if err != nil {
+		return "", err
+	}
+	return identifier.String(), nil
+}
+
+// SaveContext writes the image to the layout at each path, returning the outcome for each path.
+// The `org.opencontainers.image.ref.name` annotation of the image manifest, if any, is also set on the image
+// descriptor in the index of each layout.
+func (s *Store) SaveContext(ctx context.Context, image imgutil.IdentifiableV1Image, withName string, withAdditionalNames ...string) ([]imgutil.SaveResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	layoutImage, err := s.image(withName, image)
+	if err != nil {
+		return nil, err
+	}
+	return layoutImage.write(ctx, append([]string{withName}, withAdditionalNames...))
+}
+
+// SaveFile saves the image as a docker archive and provides its filesystem location.
+// The archive is tagged with the `org.opencontainers.image.ref.name` annotation when it is a valid image name;
+// otherwise the image is saved untagged.
+func (s *Store) SaveFile(image imgutil.IdentifiableV1Image, withName string) (string, error) {
+	layoutImage, err := s.image(withName, image)
+	if err != nil {
+		return "", err
+	}
+	return layoutImage.saveFile()
+}
+
+// SaveFileContext is the same as SaveFile, as the archive is written from disk.
+func (s *Store) SaveFileContext(_ context.Context, image imgutil.IdentifiableV1Image, withName string) (string, error) {
+	return s.SaveFile(image, withName)
+}
+
+// layers
+
+// DownloadLayersFor does nothing, as the layers of images in a layout are read from disk when they are needed.
+func (s *Store) DownloadLayersFor(_ string) error {
+	return nil
+}
+
+// DownloadLayersForContext does nothing, as the layers of images in a layout are read from disk when they are needed.
+func (s *Store) DownloadLayersForContext(_ context.Context, _ string) error {
+	return nil
+}
+
+// Layers returns no layers, as the layers of images in a layout are read from their layout.
+func (s *Store) Layers() []v1.Layer {
+	return nil
+}
+
+// helpers
+
+// image returns an Image at the given path with the given content, and the ref name from its manifest.
+// Layers missing from the layouts that the content was read from are written as empty blobs, as for sparse images.
+func (s *Store) image(path string, image v1.Image) (*Image, error) {
+	manifest, err := image.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	return &Image{
+		Image:    &Image{Image: image},
+		path:     path,
+		refName:  manifest.Annotations[ImageRefNameKey],
+		progress: s.progress,
+	}, nil
+}
+
+// pathOf returns the path of the layout of the image with the given identifier.
+func pathOf(identifier string) string {
+	idx := strings.LastIndex(identifier, identifierDelim)
+	if idx < 0 {
+		return identifier
+	}
+	if _, err := v1.NewHash(identifier[idx+1:]); err != nil {
+		// the path contains the delimiter, but has no digest
+		return identifier
+	}
+	return identifier[:idx]
+}
@@ -0,0 +1,42 @@
+package layout_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/imgutil/layout"
+	h "github.com/buildpacks/imgutil/testhelpers"
+)
+
+func TestStore(t *testing.T) {
+	spec.Run(t, "Store", testStore, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testStore(t *testing.T, when spec.G, it spec.S) {
+	when("#Save", func() {
+		it("returns an identifier that the store resolves", func() {
+			tmpDir := t.TempDir()
+			imagePath := filepath.Join(tmpDir, "some-image")
+			store, err := layout.NewStore()
+			h.AssertNil(t, err)
+			image, err := layout.NewCNBImage(imagePath)
+			h.AssertNil(t, err)
+			layerPath, _, _ := h.RandomLayer(t, tmpDir)
+			h.AssertNil(t, image.AddLayer(layerPath))
+
+			identifier, err := store.Save(image, imagePath, filepath.Join(tmpDir, "other-image"))
+			h.AssertNil(t, err)
+			expected, err := image.Identifier()
+			h.AssertNil(t, err)
+			h.AssertEq(t, identifier, expected.String())
+
+			h.AssertEq(t, store.Contains(identifier), true)
+			h.AssertNil(t, store.Delete(identifier))
+			h.AssertEq(t, store.Contains(identifier), false)
+			h.AssertEq(t, store.Contains(filepath.Join(tmpDir, "other-image")), true)
+		})
+	})
+}
@@ -0,0 +1,70 @@
+package local
+
+import (
+	"context"
+	"io"
+
+	registryName "github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/imgutil"
+)
+
+var (
+	_ imgutil.CopySource = &Image{}
+	_ imgutil.CopyTarget = &Image{}
+)
+
+// CopyContent exports the image from the daemon, as it was last saved.
+func (i *Image) CopyContent(ctx context.Context) (v1.Image, func(), error) {
+	if !i.Found() {
+		return nil, nil, errors.Errorf("image %q not found in the docker daemon", i.repoName)
+	}
+	return imgutil.ExportImage(ctx, i.docker.ImageSave, i.inspect.ID)
+}
+
+// SaveCopy loads image into the daemon as is, tags it with the name of the image, and makes it the content of the image.
+// As the daemon does not store the image manifest, only the image ID is reported in the result.
+func (i *Image) SaveCopy(ctx context.Context, image v1.Image) (imgutil.SaveResult, error) {
+	tag, err := registryName.NewTag(i.repoName, registryName.WeakValidation)
+	if err != nil {
+		return imgutil.SaveResult{}, err
+	}
+
+	pr, pw := io.Pipe()
+	defer imgutil.CloseOnDone(ctx, pr)()
+	go func() {
+		pw.CloseWithError(tarball.Write(tag, imgutil.ProgressReportingImage(image, imgutil.PhaseLoading, i.progress), pw))
+	}()
+	res, err := i.docker.ImageLoad(ctx, pr, true)
+	if err != nil {
+		pr.CloseWithError(err)
+		return imgutil.SaveResult{}, errors.Wrapf(err, "loading image %q", i.repoName)
+	}
+	responseErr := checkResponseError(res.Body)
+	if err := ensureReaderClosed(res.Body); responseErr == nil {
+		responseErr = err
+	}
+	if responseErr != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return imgutil.SaveResult{}, ctxErr
+		}
+		return imgutil.SaveResult{}, errors.Wrapf(responseErr, "loading image %q", i.repoName)
+	}
+
+	inspect, _, err := i.docker.ImageInspectWithRaw(ctx, tag.Name())
+	if err != nil {
+		return imgutil.SaveResult{}, errors.Wrapf(err, "saving image %q", i.repoName)
+	}
+	configFile, err := image.ConfigFile()
+	if err != nil {
+		return imgutil.SaveResult{}, err
+	}
+	i.inspect = inspect
+	i.history = imgutil.NormalizedHistory(configFile.History, len(inspect.RootFS.Layers))
+	i.layerPaths = make([]string, len(inspect.RootFS.Layers))
+	i.baseLayersDownloaded = false
+	return imgutil.SaveResult{Name: i.repoName, ImageID: inspect.ID}, nil
+}
@@ -20,16 +20,24 @@ import (
 	"github.com/buildpacks/imgutil"
 )
 
+var _ imgutil.RebaseReporter = &Image{}
+
 type Image struct {
-	docker           DockerClient
-	repoName         string
-	inspect          types.ImageInspect
-	history          []v1.History
-	layerPaths       []string
-	prevImage        *Image // reused layers will be fetched from prevImage
-	downloadBaseOnce *sync.Once
-	createdAt        time.Time
-	withHistory      bool
+	docker        DockerClient
+	repoName      string
+	inspect       types.ImageInspect
+	history       []v1.History
+	layerPaths    []string
+	prevImage     *Image     // reused layers will be fetched from prevImage
+	baseLayers    []v1.Layer // layers of a new base image from another store, read when they are missing from the daemon
+	baseLayersDir string     // baseLayers read to disk, removed once the image is saved
+	createdAt     time.Time
+	osFeatures    []string // the daemon does not report the OS features of images, so only those requested are known
+	withHistory   bool
+	progress      imgutil.ProgressHandler
+
+	downloadBaseMu       sync.Mutex
+	baseLayersDownloaded bool
 }
 
 // DockerClient is subset of client.CommonAPIClient required by this package
@@ -46,10 +54,26 @@ type DockerClient interface {
 
 // getters
 
+// Annotations is not supported, as the docker daemon does not store manifest annotations.
+func (i *Image) Annotations() (map[string]string, error) {
+	return nil, imgutil.ErrAnnotationsNotSupported
+}
+
 func (i *Image) Architecture() (string, error) {
 	return i.inspect.Architecture, nil
 }
 
+// ConfigFile returns the config file the image would be saved with. Fields that the daemon does not report,
+// such as the diff IDs of layers that have not been saved yet, are taken from the pending changes to the image.
+func (i *Image) ConfigFile() (*v1.ConfigFile, error) {
+	cfg, err := v1Config(i.inspect, i.createdAt, append([]v1.History(nil), i.history...))
+	if err != nil {
+		return nil, err
+	}
+	cfg.OSFeatures = i.osFeatures
+	return &cfg, nil
+}
+
 func (i *Image) CreatedAt() (time.Time, error) {
 	createdAtTime := i.inspect.Created
 	createdTime, err := time.Parse(time.RFC3339Nano, createdAtTime)
@@ -78,6 +102,11 @@ func (i *Image) Found() bool {
 	return i.inspect.ID != ""
 }
 
+// FoundContext is the same as Found, as the presence of the image in the daemon is determined when the image is created.
+func (i *Image) FoundContext(_ context.Context) bool {
+	return i.Found()
+}
+
 func (i *Image) Valid() bool {
 	return i.Found()
 }
@@ -87,22 +116,27 @@ func (i *Image) GetAnnotateRefName() (string, error) {
 }
 
 func (i *Image) GetLayer(diffID string) (io.ReadCloser, error) {
+	return i.GetLayerContext(context.Background(), diffID)
+}
+
+// GetLayerContext is like GetLayer, but aborts downloading layers from the daemon when ctx is done.
+func (i *Image) GetLayerContext(ctx context.Context, diffID string) (io.ReadCloser, error) {
 	for l := range i.inspect.RootFS.Layers {
 		if i.inspect.RootFS.Layers[l] != diffID {
 			continue
 		}
 		if i.layerPaths[l] == "" {
-			if err := i.downloadBaseLayersOnce(); err != nil {
+			if err := i.downloadBaseLayersOnce(ctx); err != nil {
 				return nil, err
 			}
 			if i.layerPaths[l] == "" {
-				return nil, fmt.Errorf("fetching layer %q from daemon", diffID)
+				return nil, imgutil.Errorf(imgutil.ErrLayerNotFound, "fetching layer %q from daemon", diffID)
 			}
 		}
 		return os.Open(i.layerPaths[l])
 	}
 
-	return nil, fmt.Errorf("image %q does not contain layer with diff ID %q", i.repoName, diffID)
+	return nil, imgutil.Errorf(imgutil.ErrLayerNotFound, "image %q does not contain layer with diff ID %q", i.repoName, diffID)
 }
 
 func (i *Image) History() ([]v1.History, error) {
@@ -181,6 +215,10 @@ func (i *Image) Rename(name string) {
 	i.repoName = name
 }
 
+func (i *Image) SetAnnotation(key, value string) error {
+	return imgutil.ErrAnnotationsNotSupported
+}
+
 func (i *Image) SetArchitecture(architecture string) error {
 	i.inspect.Architecture = architecture
 	return nil
@@ -231,7 +269,7 @@ func (i *Image) SetLabel(key, val string) error {
 
 func (i *Image) SetOS(osVal string) error {
 	if osVal != i.inspect.Os {
-		return fmt.Errorf("invalid os: must match the daemon: %q", i.inspect.Os)
+		return imgutil.Errorf(imgutil.ErrPlatformMismatch, "invalid os: must match the daemon: %q", i.inspect.Os)
 	}
 	return nil
 }
@@ -279,6 +317,10 @@ func (i *Image) AddLayerWithDiffIDAndHistory(path, diffID string, history v1.His
 }
 
 func (i *Image) Delete() error {
+	return i.DeleteContext(context.Background())
+}
+
+func (i *Image) DeleteContext(ctx context.Context) error {
 	if !i.Found() {
 		return nil
 	}
@@ -286,10 +328,36 @@ func (i *Image) Delete() error {
 		Force:         true,
 		PruneChildren: true,
 	}
-	_, err := i.docker.ImageRemove(context.Background(), i.inspect.ID, options)
+	_, err := i.docker.ImageRemove(ctx, i.inspect.ID, options)
 	return err
 }
 
+// MutateConfigFile calls withFunc on the config file of the image, and maps the result back onto the image.
+// The OS must still match the daemon, and the mutation must pass imgutil.ValidateConfigFileMutation; otherwise, an error
+// is returned and the image is left as is.
+func (i *Image) MutateConfigFile(withFunc func(c *v1.ConfigFile)) error {
+	configFile, err := i.ConfigFile()
+	if err != nil {
+		return err
+	}
+	before := configFile.DeepCopy()
+	withFunc(configFile)
+	if configFile.OS != i.inspect.Os {
+		return imgutil.Errorf(imgutil.ErrPlatformMismatch, "invalid os: must match the daemon: %q", i.inspect.Os)
+	}
+	if err = imgutil.ValidateConfigFileMutation(before, configFile); err != nil {
+		return err
+	}
+	i.inspect.Architecture = configFile.Architecture
+	i.inspect.OsVersion = configFile.OSVersion
+	i.inspect.Variant = configFile.Variant
+	i.osFeatures = configFile.OSFeatures
+	i.inspect.Config = dockerConfig(configFile.Config)
+	i.createdAt = configFile.Created.Time
+	i.history = configFile.History
+	return nil
+}
+
 func (i *Image) Rebase(baseTopLayer string, newBase imgutil.Image) error {
 	ctx := context.Background()
 
@@ -306,22 +374,103 @@ func (i *Image) Rebase(baseTopLayer string, newBase imgutil.Image) error {
 	}
 
 	// DOWNLOAD IMAGE
-	if err := i.downloadBaseLayersOnce(); err != nil {
+	if err := i.downloadBaseLayersOnce(ctx); err != nil {
 		return err
 	}
 
 	// SWITCH BASE LAYERS
+	if kind := newBase.Kind(); kind != "local" && kind != "locallayout" {
+		return i.rebaseOnto(ctx, newBase, keepLayersIdx)
+	}
 	newBaseInspect, _, err := i.docker.ImageInspectWithRaw(ctx, newBase.Name())
 	if err != nil {
 		return errors.Wrapf(err, "read config for new base image %q", newBase)
 	}
 	i.inspect.ID = newBaseInspect.ID
-	i.downloadBaseOnce = &sync.Once{}
+	i.inspect.Architecture = newBaseInspect.Architecture
+	i.inspect.OsVersion = newBaseInspect.OsVersion
+	i.baseLayers = nil
+	i.baseLayersDownloaded = false
 	i.inspect.RootFS.Layers = append(newBaseInspect.RootFS.Layers, i.inspect.RootFS.Layers[keepLayersIdx:]...)
 	i.layerPaths = append(make([]string, len(newBaseInspect.RootFS.Layers)), i.layerPaths[keepLayersIdx:]...)
 	return nil
 }
 
+// RebaseWithReport is like Rebase, but first validates the new base as requested and reports what the rebase changed.
+func (i *Image) RebaseWithReport(baseTopLayer string, newBase imgutil.Image, ops ...imgutil.RebaseOption) (imgutil.RebaseReport, error) {
+	return imgutil.RebaseWithReport(i, baseTopLayer, newBase, func() error {
+		return i.Rebase(baseTopLayer, newBase)
+	}, ops...)
+}
+
+// rebaseOnto switches the base layers to those of a new base image that is not in the daemon.
+// If the daemon has an image with the name and layers of the new base, it is used as the new base instead.
+// Otherwise, layers that the image already has are reused, and the others are only read when the image is saved
+// and the daemon does not have them.
+func (i *Image) rebaseOnto(ctx context.Context, newBase imgutil.Image, keepLayersIdx int) error {
+	newBaseImage, err := imgutil.NewBaseImage(newBase)
+	if err != nil {
+		return err
+	}
+	newBaseConfig, err := newBaseImage.ConfigFile()
+	if err != nil {
+		return errors.Wrapf(err, "read config for new base image %q", newBase.Name())
+	}
+	layers, err := newBaseImage.Layers()
+	if err != nil {
+		return errors.Wrapf(err, "read layers for new base image %q", newBase.Name())
+	}
+	if newBaseConfig.OS != i.inspect.Os {
+		return imgutil.Errorf(imgutil.ErrPlatformMismatch, "invalid os of new base image %q: must match the daemon: %q", newBase.Name(), i.inspect.Os)
+	}
+	diffIDs := make([]string, len(newBaseConfig.RootFS.DiffIDs))
+	for idx, diffID := range newBaseConfig.RootFS.DiffIDs {
+		diffIDs[idx] = diffID.String()
+	}
+	layerPaths := make([]string, len(diffIDs))
+	if daemonBase, _, err := i.docker.ImageInspectWithRaw(ctx, newBase.Name()); err == nil && equalLayers(daemonBase.RootFS.Layers, diffIDs) {
+		i.inspect.ID = daemonBase.ID
+		layers = nil
+	} else {
+		for idx, diffID := range diffIDs {
+			layerPaths[idx] = i.layerPath(diffID)
+		}
+	}
+	i.inspect.Architecture = newBaseConfig.Architecture
+	i.inspect.OsVersion = newBaseConfig.OSVersion
+	i.baseLayers = layers
+	i.baseLayersDownloaded = false
+	i.inspect.RootFS.Layers = append(diffIDs, i.inspect.RootFS.Layers[keepLayersIdx:]...)
+	i.layerPaths = append(layerPaths, i.layerPaths[keepLayersIdx:]...)
+	return nil
+}
+
+// layerPath returns the path of the layer with the given diff ID if the image has it on disk.
+func (i *Image) layerPath(diffID string) string {
+	for idx, path := range i.layerPaths {
+		if path != "" && i.inspect.RootFS.Layers[idx] == diffID {
+			return path
+		}
+	}
+	return ""
+}
+
+func equalLayers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+func (i *Image) RemoveAnnotation(key string) error {
+	return imgutil.ErrAnnotationsNotSupported
+}
+
 func (i *Image) RemoveLabel(key string) error {
 	delete(i.inspect.Config.Labels, key)
 	return nil
@@ -336,7 +485,7 @@ func (i *Image) ReuseLayer(diffID string) error {
 			return i.AddLayerWithDiffIDAndHistory(i.prevImage.layerPaths[idx], diffID, i.prevImage.history[idx])
 		}
 	}
-	return fmt.Errorf("SHA %s was not found in %s", diffID, i.prevImage.Name())
+	return imgutil.Errorf(imgutil.ErrLayerNotFound, "SHA %s was not found in %s", diffID, i.prevImage.Name())
 }
 
 func (i *Image) ReuseLayerWithHistory(diffID string, history v1.History) error {
@@ -348,7 +497,7 @@ func (i *Image) ReuseLayerWithHistory(diffID string, history v1.History) error {
 			return i.AddLayerWithDiffIDAndHistory(i.prevImage.layerPaths[idx], diffID, history)
 		}
 	}
-	return fmt.Errorf("SHA %s was not found in %s", diffID, i.prevImage.Name())
+	return imgutil.Errorf(imgutil.ErrLayerNotFound, "SHA %s was not found in %s", diffID, i.prevImage.Name())
 }
 
 func (i *Image) ensureLayers() error {
@@ -356,9 +505,9 @@ func (i *Image) ensureLayers() error {
 		return errors.New("failed to reuse layer because no previous image was provided")
 	}
 	if !i.prevImage.Found() {
-		return fmt.Errorf("failed to reuse layer because previous image %q was not found in daemon", i.prevImage.repoName)
+		return imgutil.Errorf(imgutil.ErrImageNotFound, "failed to reuse layer because previous image %q was not found in daemon", i.prevImage.repoName)
 	}
-	if err := i.prevImage.downloadBaseLayersOnce(); err != nil {
+	if err := i.prevImage.downloadBaseLayersOnce(context.Background()); err != nil {
 		return err
 	}
 	return nil
@@ -3,9 +3,11 @@ package local_test
 import (
 	"archive/tar"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -19,6 +21,7 @@ import (
 	"github.com/sclevine/spec/report"
 
 	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/layout"
 	"github.com/buildpacks/imgutil/local"
 	"github.com/buildpacks/imgutil/remote"
 	h "github.com/buildpacks/imgutil/testhelpers"
@@ -135,6 +138,24 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 					h.AssertEq(t, len(inspect.RootFS.Layers), 0)
 				}
 			})
+
+			it("writes the variant and the os features to the config file", func() {
+				img, err := local.NewImage(
+					newTestImageName(),
+					dockerClient,
+					local.WithDefaultPlatform(imgutil.Platform{
+						Architecture: "arm",
+						OS:           daemonOS,
+						Variant:      "v7",
+					}.WithOSFeatures("some-feature")),
+				)
+				h.AssertNil(t, err)
+
+				configFile, err := img.ConfigFile()
+				h.AssertNil(t, err)
+				h.AssertEq(t, configFile.Variant, "v7")
+				h.AssertEq(t, configFile.OSFeatures, []string{"some-feature"})
+			})
 		})
 
 		when("#FromBaseImage", func() {
@@ -435,6 +456,26 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#Annotations", func() {
+		it("returns an error", func() {
+			img, err := local.NewImage(newTestImageName(), dockerClient)
+			h.AssertNil(t, err)
+
+			_, err = img.Annotations()
+			h.AssertError(t, err, "manifest annotations are not supported for images in a docker daemon")
+		})
+	})
+
+	when("#SetAnnotation", func() {
+		it("returns an error", func() {
+			img, err := local.NewImage(newTestImageName(), dockerClient)
+			h.AssertNil(t, err)
+
+			err = img.SetAnnotation("some-key", "some-value")
+			h.AssertError(t, err, "manifest annotations are not supported for images in a docker daemon")
+		})
+	})
+
 	when("#Labels", func() {
 		when("image exists with labels", func() {
 			var repoName = newTestImageName()
@@ -1014,6 +1055,63 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#MutateConfigFile", func() {
+		var repoName = newTestImageName()
+
+		it.After(func() {
+			h.AssertNil(t, h.DockerRmi(dockerClient, repoName))
+		})
+
+		it("maps the config file onto the image", func() {
+			img, err := local.NewImage(repoName, dockerClient)
+			h.AssertNil(t, err)
+
+			err = img.MutateConfigFile(func(c *v1.ConfigFile) {
+				c.Config.User = "some-user"
+				c.Config.ExposedPorts = map[string]struct{}{"8080/tcp": {}}
+				c.Config.StopSignal = "SIGKILL"
+			})
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, img.Save())
+
+			inspect, _, err := dockerClient.ImageInspectWithRaw(context.TODO(), repoName)
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, inspect.Config.User, "some-user")
+			_, ok := inspect.Config.ExposedPorts["8080/tcp"]
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, inspect.Config.StopSignal, "SIGKILL")
+		})
+
+		it("errors when the layers or the number of history entries are changed", func() {
+			img, err := local.NewImage(repoName, dockerClient)
+			h.AssertNil(t, err)
+			layerPath, err := h.CreateSingleFileLayerTar("/some-file.txt", "some-content", daemonOS)
+			h.AssertNil(t, err)
+			defer os.Remove(layerPath)
+			h.AssertNil(t, img.AddLayer(layerPath))
+
+			err = img.MutateConfigFile(func(c *v1.ConfigFile) {
+				c.Config.User = "some-user"
+				c.RootFS.DiffIDs = c.RootFS.DiffIDs[:len(c.RootFS.DiffIDs)-1]
+			})
+			h.AssertEq(t, errors.Is(err, imgutil.ErrNotImplemented), true)
+
+			err = img.MutateConfigFile(func(c *v1.ConfigFile) {
+				c.Config.User = "some-user"
+				c.History = append(c.History, v1.History{CreatedBy: "some-history"})
+			})
+			h.AssertError(t, err, "invalid history")
+
+			// the image is left as is
+			h.AssertNil(t, img.Save())
+			inspect, _, err := dockerClient.ImageInspectWithRaw(context.TODO(), repoName)
+			h.AssertNil(t, err)
+			h.AssertEq(t, inspect.Config.User, "")
+		})
+	})
+
 	when("#SetEntrypoint", func() {
 		var repoName = newTestImageName()
 
@@ -1256,6 +1354,39 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 				h.AssertEq(t, afterInspect.OsVersion, beforeInspect.OsVersion)
 				h.AssertEq(t, afterInspect.Architecture, beforeInspect.Architecture)
 			})
+
+			it("switches the base to a new base that is not in the daemon", func() {
+				if daemonOS == "windows" {
+					t.Skip("windows test")
+				}
+				beforeInspect, _, err := dockerClient.ImageInspectWithRaw(context.TODO(), repoName)
+				h.AssertNil(t, err)
+
+				newBaseImg, err := layout.NewImage(
+					filepath.Join(t.TempDir(), "new-base"),
+					layout.WithDefaultPlatform(imgutil.Platform{OS: beforeInspect.Os, Architecture: beforeInspect.Architecture}),
+				)
+				h.AssertNil(t, err)
+				newBaseLayerPath, err := h.CreateSingleFileLayerTar("/new-base.txt", "layout-new-base", daemonOS)
+				h.AssertNil(t, err)
+				defer os.Remove(newBaseLayerPath)
+				h.AssertNil(t, newBaseImg.AddLayer(newBaseLayerPath))
+				newBaseLayerDiffID := h.FileDiffID(t, newBaseLayerPath)
+
+				img, err := local.NewImage(repoName, dockerClient, local.FromBaseImage(repoName))
+				h.AssertNil(t, err)
+				h.AssertNil(t, img.Rebase(oldTopLayer, newBaseImg))
+
+				h.AssertNil(t, img.Save())
+
+				afterInspect, _, err := dockerClient.ImageInspectWithRaw(context.TODO(), repoName)
+				h.AssertNil(t, err)
+				h.AssertEq(t, afterInspect.RootFS.Layers, []string{newBaseLayerDiffID, imgLayer1DiffID, imgLayer2DiffID})
+
+				// the image can be saved again once the layers of the new base are only in the daemon
+				h.AssertNil(t, img.SetLabel("some-key", "some-value"))
+				h.AssertNil(t, img.Save())
+			})
 		})
 	})
 
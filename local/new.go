@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types"
@@ -45,13 +44,13 @@ func NewImage(repoName string, dockerClient DockerClient, ops ...ImageOption) (*
 	inspect := defaultInspect(platform)
 
 	image := &Image{
-		docker:           dockerClient,
-		repoName:         repoName,
-		inspect:          inspect,
-		history:          make([]v1.History, len(inspect.RootFS.Layers)),
-		layerPaths:       make([]string, len(inspect.RootFS.Layers)),
-		downloadBaseOnce: &sync.Once{},
-		withHistory:      imageOpts.withHistory,
+		docker:      dockerClient,
+		repoName:    repoName,
+		inspect:     inspect,
+		history:     make([]v1.History, len(inspect.RootFS.Layers)),
+		layerPaths:  make([]string, len(inspect.RootFS.Layers)),
+		withHistory: imageOpts.withHistory,
+		progress:    imageOpts.progress,
 	}
 
 	if imageOpts.prevImageRepoName != "" {
@@ -66,6 +65,10 @@ func NewImage(repoName string, dockerClient DockerClient, ops ...ImageOption) (*
 		}
 	}
 
+	if !image.Found() {
+		image.osFeatures = platform.OSFeatures()
+	}
+
 	if image.inspect.Os == "windows" {
 		if err := prepareNewWindowsImage(image); err != nil {
 			return nil, err
@@ -99,7 +102,7 @@ func defaultPlatform(dockerClient DockerClient) (imgutil.Platform, error) {
 
 func validatePlatformOption(defaultPlatform imgutil.Platform, optionPlatform imgutil.Platform) error {
 	if optionPlatform.OS != "" && optionPlatform.OS != defaultPlatform.OS {
-		return fmt.Errorf("invalid os: platform os %q must match the daemon os %q", optionPlatform.OS, defaultPlatform.OS)
+		return imgutil.Errorf(imgutil.ErrPlatformMismatch, "invalid os: platform os %q must match the daemon os %q", optionPlatform.OS, defaultPlatform.OS)
 	}
 
 	return nil
@@ -110,6 +113,7 @@ func defaultInspect(platform imgutil.Platform) types.ImageInspect {
 		Os:           platform.OS,
 		Architecture: platform.Architecture,
 		OsVersion:    platform.OSVersion,
+		Variant:      platform.Variant,
 		Config:       &container.Config{},
 	}
 }
@@ -17,6 +17,7 @@ type options struct {
 	withHistory       bool
 	createdAt         time.Time
 	config            *container.Config
+	progress          imgutil.ProgressHandler
 }
 
 // FromBaseImage loads an existing image as the config and layers for the new image.
@@ -44,7 +45,7 @@ func WithConfig(config *container.Config) ImageOption {
 	}
 }
 
-// WithDefaultPlatform provides Architecture/OS/OSVersion defaults for the new image.
+// WithDefaultPlatform provides Architecture/OS/OSVersion/Variant/OSFeatures defaults for the new image.
 // Defaults for a new image are ignored when FromBaseImage returns an image.
 func WithDefaultPlatform(platform imgutil.Platform) ImageOption {
 	return func(i *options) error {
@@ -71,3 +72,12 @@ func WithPreviousImage(imageName string) ImageOption {
 		return nil
 	}
 }
+
+// WithProgress registers a handler that is called with the progress of each layer
+// while the image is loaded into the daemon or written by SaveFile.
+func WithProgress(handler imgutil.ProgressHandler) ImageOption {
+	return func(opts *options) error {
+		opts.progress = handler
+		return nil
+	}
+}
@@ -14,59 +14,93 @@ import (
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/jsonmessage"
 	registryName "github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/pkg/errors"
 
 	"github.com/buildpacks/imgutil"
 )
 
 func (i *Image) Save(additionalNames ...string) error {
-	return i.SaveAs(i.Name(), additionalNames...)
+	return i.SaveAsContext(context.Background(), i.Name(), additionalNames...)
+}
+
+func (i *Image) SaveContext(ctx context.Context, additionalNames ...string) error {
+	return i.SaveAsContext(ctx, i.Name(), additionalNames...)
 }
 
 func (i *Image) SaveAs(name string, additionalNames ...string) error {
+	return i.SaveAsContext(context.Background(), name, additionalNames...)
+}
+
+// SaveAsContext is like SaveAs, but aborts loading the image into the daemon when ctx is done.
+func (i *Image) SaveAsContext(ctx context.Context, name string, additionalNames ...string) error {
+	_, err := i.saveAs(ctx, name, additionalNames...)
+	return err
+}
+
+// SaveWithResult is like Save, but also returns the outcome of saving the image as each name.
+// As the daemon does not store the image manifest, only the image ID is reported for each name.
+func (i *Image) SaveWithResult(additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return i.saveAs(context.Background(), i.Name(), additionalNames...)
+}
+
+// SaveAsWithResult is like SaveAs, but also returns the outcome of saving the image as each name.
+// As the daemon does not store the image manifest, only the image ID is reported for each name.
+func (i *Image) SaveAsWithResult(name string, additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return i.saveAs(context.Background(), name, additionalNames...)
+}
+
+func (i *Image) saveAs(ctx context.Context, name string, additionalNames ...string) ([]imgutil.SaveResult, error) {
 	var (
 		inspect types.ImageInspect
 		err     error
 	)
-	canOmitBaseLayers := !usesContainerdStorage(i.docker)
+	allNames := append([]string{name}, additionalNames...)
+	canOmitBaseLayers := !usesContainerdStorage(ctx, i.docker)
 	if canOmitBaseLayers {
 		// During the first save attempt some layers may be excluded.
 		// The docker daemon allows this if the given set of layers already exists in the daemon in the given order.
-		inspect, err = i.doSaveAs(name)
+		inspect, err = i.doSaveAs(ctx, name)
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
 	}
 	if !canOmitBaseLayers || err != nil {
 		// populate all layer paths and try again without the above performance optimization.
-		if err := i.downloadBaseLayersOnce(); err != nil {
-			return err
+		if err := i.downloadBaseLayersOnce(ctx); err != nil {
+			return nil, err
 		}
 
-		inspect, err = i.doSaveAs(name)
+		inspect, err = i.doSaveAs(ctx, name)
 		if err != nil {
-			saveErr := imgutil.SaveError{}
-			for _, n := range append([]string{name}, additionalNames...) {
-				saveErr.Errors = append(saveErr.Errors, imgutil.SaveDiagnostic{ImageName: n, Cause: err})
+			var results []imgutil.SaveResult
+			for _, n := range allNames {
+				results = append(results, imgutil.SaveResult{Name: n, Err: err})
 			}
-			return saveErr
+			return results, imgutil.SaveErrorFrom(results)
 		}
 	}
 	i.inspect = inspect
-
-	var errs []imgutil.SaveDiagnostic
-	for _, n := range append([]string{name}, additionalNames...) {
-		if err := i.docker.ImageTag(context.Background(), i.inspect.ID, n); err != nil {
-			errs = append(errs, imgutil.SaveDiagnostic{ImageName: n, Cause: err})
-		}
+	if i.baseLayersDir != "" {
+		// the daemon has the layers of the new base now, and they are exported from it if they are needed again
+		i.removeFetchedBaseLayers()
+		i.baseLayers = nil
 	}
 
-	if len(errs) > 0 {
-		return imgutil.SaveError{Errors: errs}
+	var results []imgutil.SaveResult
+	for _, n := range allNames {
+		result := imgutil.SaveResult{Name: n, ImageID: i.inspect.ID}
+		if err := i.docker.ImageTag(ctx, i.inspect.ID, n); err != nil {
+			result = imgutil.SaveResult{Name: n, Err: err}
+		}
+		results = append(results, result)
 	}
 
-	return nil
+	return results, imgutil.SaveErrorFrom(results)
 }
 
-func usesContainerdStorage(docker DockerClient) bool {
-	info, err := docker.Info(context.Background())
+func usesContainerdStorage(ctx context.Context, docker DockerClient) bool {
+	info, err := docker.Info(ctx)
 	if err != nil {
 		return false
 	}
@@ -80,9 +114,8 @@ func usesContainerdStorage(docker DockerClient) bool {
 	return false
 }
 
-func (i *Image) doSaveAs(name string) (types.ImageInspect, error) {
-	ctx := context.Background()
-	done := make(chan error)
+func (i *Image) doSaveAs(ctx context.Context, name string) (types.ImageInspect, error) {
+	done := make(chan error, 1)
 
 	t, err := registryName.NewTag(name, registryName.WeakValidation)
 	if err != nil {
@@ -94,9 +127,12 @@ func (i *Image) doSaveAs(name string) (types.ImageInspect, error) {
 
 	pr, pw := io.Pipe()
 	defer pw.Close()
+	defer imgutil.CloseOnDone(ctx, pr)()
 	go func() {
 		res, err := i.docker.ImageLoad(ctx, pr, true)
 		if err != nil {
+			// unblock the tar producer, which would otherwise wait forever on the pipe
+			pr.CloseWithError(err)
 			done <- err
 			return
 		}
@@ -116,8 +152,12 @@ func (i *Image) doSaveAs(name string) (types.ImageInspect, error) {
 	}()
 
 	tw := tar.NewWriter(pw)
-	_, err = i.addImageToTar(tw, repoName)
+	_, err = i.addImageToTar(tw, repoName, imgutil.PhaseLoading)
 	if err != nil {
+		pw.CloseWithError(err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return types.ImageInspect{}, ctxErr
+		}
 		return types.ImageInspect{}, err
 	}
 	defer tw.Close()
@@ -126,10 +166,13 @@ func (i *Image) doSaveAs(name string) (types.ImageInspect, error) {
 	pw.Close()
 	err = <-done
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return types.ImageInspect{}, ctxErr
+		}
 		return types.ImageInspect{}, errors.Wrapf(err, "loading image %q. first error", i.repoName)
 	}
 
-	inspect, _, err := i.docker.ImageInspectWithRaw(context.Background(), i.repoName)
+	inspect, _, err := i.docker.ImageInspectWithRaw(ctx, i.repoName)
 	if err != nil {
 		if client.IsErrNotFound(err) {
 			return types.ImageInspect{}, errors.Wrapf(err, "saving image %q", i.repoName)
@@ -140,25 +183,31 @@ func (i *Image) doSaveAs(name string) (types.ImageInspect, error) {
 	return inspect, nil
 }
 
-// downloadBaseLayersOnce exports the base image from the daemon and populates layerPaths the first time it is called.
+// downloadBaseLayersOnce exports the base image from the daemon and populates layerPaths the first time it succeeds.
 // subsequent calls do nothing.
-func (i *Image) downloadBaseLayersOnce() error {
-	var err error
-	if !i.Found() {
+func (i *Image) downloadBaseLayersOnce(ctx context.Context) error {
+	if !i.Found() && len(i.baseLayers) == 0 {
 		return nil
 	}
-	i.downloadBaseOnce.Do(func() {
-		err = i.downloadBaseLayers()
-	})
+	i.downloadBaseMu.Lock()
+	defer i.downloadBaseMu.Unlock()
+	if i.baseLayersDownloaded {
+		return nil
+	}
+	var err error
+	if len(i.baseLayers) > 0 {
+		err = i.fetchBaseLayers(ctx)
+	} else {
+		err = i.downloadBaseLayers(ctx)
+	}
 	if err != nil {
 		return errors.Wrap(err, "fetching base layers")
 	}
-	return err
+	i.baseLayersDownloaded = true
+	return nil
 }
 
-func (i *Image) downloadBaseLayers() error {
-	ctx := context.Background()
-
+func (i *Image) downloadBaseLayers(ctx context.Context) error {
 	imageReader, err := i.docker.ImageSave(ctx, []string{i.inspect.ID})
 	if err != nil {
 		return errors.Wrapf(err, "saving base image with ID %q from the docker daemon", i.inspect.ID)
@@ -222,7 +271,68 @@ func (i *Image) downloadBaseLayers() error {
 	return nil
 }
 
-func (i *Image) addImageToTar(tw *tar.Writer, repoName string) (string, error) {
+// fetchBaseLayers writes the layers of a new base image from another store that are not on disk yet to a temporary
+// directory and populates layerPaths. The directory is removed once the image is saved.
+func (i *Image) fetchBaseLayers(ctx context.Context) error {
+	tmpDir, err := os.MkdirTemp("", "imgutil.local.image.")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temp dir")
+	}
+	for idx, layer := range i.baseLayers {
+		if i.layerPaths[idx] != "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			i.removeBaseLayers(tmpDir)
+			return err
+		}
+		path := filepath.Join(tmpDir, fmt.Sprintf("%d.tar", idx))
+		if err := writeLayer(layer, path); err != nil {
+			i.removeBaseLayers(tmpDir)
+			return errors.Wrapf(err, "fetching layer %s", i.inspect.RootFS.Layers[idx])
+		}
+		i.layerPaths[idx] = path
+	}
+	i.baseLayersDir = tmpDir
+	return nil
+}
+
+// removeFetchedBaseLayers removes the layers written to disk by fetchBaseLayers, which fetches them again if needed.
+func (i *Image) removeFetchedBaseLayers() {
+	if i.baseLayersDir == "" {
+		return
+	}
+	i.removeBaseLayers(i.baseLayersDir)
+	i.baseLayersDir = ""
+	i.baseLayersDownloaded = false
+}
+
+// removeBaseLayers removes the layers of a new base image written to dir by fetchBaseLayers.
+func (i *Image) removeBaseLayers(dir string) {
+	for idx, path := range i.layerPaths {
+		if filepath.Dir(path) == dir {
+			i.layerPaths[idx] = ""
+		}
+	}
+	_ = os.RemoveAll(dir)
+}
+
+func writeLayer(layer v1.Layer, path string) error {
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	f, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, rc)
+	return err
+}
+
+func (i *Image) addImageToTar(tw *tar.Writer, repoName string, phase imgutil.ProgressPhase) (string, error) {
 	configFile, err := i.newConfigFile()
 	if err != nil {
 		return "", errors.Wrap(err, "generating config file")
@@ -235,7 +345,7 @@ func (i *Image) addImageToTar(tw *tar.Writer, repoName string) (string, error) {
 
 	var blankIdx int
 	var layerPaths []string
-	for _, path := range i.layerPaths {
+	for idx, path := range i.layerPaths {
 		if path == "" {
 			layerName := fmt.Sprintf("blank_%d", blankIdx)
 			blankIdx++
@@ -251,7 +361,7 @@ func (i *Image) addImageToTar(tw *tar.Writer, repoName string) (string, error) {
 				return "", err
 			}
 			defer f.Close()
-			if err := addFileToTar(tw, layerName, f); err != nil {
+			if err := i.addLayerToTar(tw, layerName, f, i.inspect.RootFS.Layers[idx], phase); err != nil {
 				return "", err
 			}
 			f.Close()
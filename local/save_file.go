@@ -12,10 +12,14 @@ import (
 	"time"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
 	registryName "github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
+
+	"github.com/buildpacks/imgutil"
 )
 
 func (i *Image) SaveFile() (string, error) {
@@ -34,9 +38,11 @@ func (i *Image) SaveFile() (string, error) {
 	// (1) WithPreviousImage(), or (2) FromBaseImage().
 	// The former is only relevant if ReuseLayers() has been called which takes care of resolving them.
 	// The latter case needs to be handled explicitly.
-	if err := i.downloadBaseLayersOnce(); err != nil {
+	if err := i.downloadBaseLayersOnce(context.Background()); err != nil {
 		return "", errors.Wrap(err, "failed to fetch base layers")
 	}
+	// the archive is not loaded into the daemon, so fetched layers of a new base are removed once it is written
+	defer i.removeFetchedBaseLayers()
 
 	errs, _ := errgroup.WithContext(context.Background())
 	pr, pw := io.Pipe()
@@ -63,7 +69,7 @@ func (i *Image) SaveFile() (string, error) {
 		// returns valid 'name:tag' appending 'latest', if missing tag
 		repoName := t.Name()
 
-		_, err = i.addImageToTar(tw, repoName)
+		_, err = i.addImageToTar(tw, repoName, imgutil.PhaseWriting)
 		return err
 	})
 
@@ -84,12 +90,13 @@ func (i *Image) newConfigFile() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	cfg.OSFeatures = i.osFeatures
 	return json.Marshal(cfg)
 }
 
 // helpers
 
-func addFileToTar(tw *tar.Writer, name string, contents *os.File) error {
+func (i *Image) addLayerToTar(tw *tar.Writer, name string, contents *os.File, diffID string, phase imgutil.ProgressPhase) error {
 	fi, err := contents.Stat()
 	if err != nil {
 		return err
@@ -98,7 +105,11 @@ func addFileToTar(tw *tar.Writer, name string, contents *os.File) error {
 	if err := tw.WriteHeader(hdr); err != nil {
 		return err
 	}
-	_, err = io.Copy(tw, contents)
+	digest, err := v1.NewHash(diffID)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, imgutil.NewProgressReader(contents, digest, fi.Size(), phase, i.progress))
 	return err
 }
 
@@ -240,6 +251,7 @@ func v1Config(inspect types.ImageInspect, createdAt time.Time, history []v1.Hist
 		History:      history,
 		OS:           inspect.Os,
 		OSVersion:    inspect.OsVersion,
+		Variant:      inspect.Variant,
 		RootFS: v1.RootFS{
 			Type:    "layers",
 			DiffIDs: diffIDs,
@@ -247,3 +259,50 @@ func v1Config(inspect types.ImageInspect, createdAt time.Time, history []v1.Hist
 		Config: config,
 	}, nil
 }
+
+// dockerConfig is the inverse of the mapping of the container config in v1Config.
+func dockerConfig(config v1.Config) *container.Config {
+	var healthcheck *container.HealthConfig
+	if config.Healthcheck != nil {
+		healthcheck = &container.HealthConfig{
+			Test:        config.Healthcheck.Test,
+			Interval:    config.Healthcheck.Interval,
+			Timeout:     config.Healthcheck.Timeout,
+			StartPeriod: config.Healthcheck.StartPeriod,
+			Retries:     config.Healthcheck.Retries,
+		}
+	}
+	var exposedPorts nat.PortSet
+	if config.ExposedPorts != nil {
+		exposedPorts = make(nat.PortSet, len(config.ExposedPorts))
+		for key, val := range config.ExposedPorts {
+			exposedPorts[nat.Port(key)] = val
+		}
+	}
+	return &container.Config{
+		AttachStderr:    config.AttachStderr,
+		AttachStdin:     config.AttachStdin,
+		AttachStdout:    config.AttachStdout,
+		Cmd:             config.Cmd,
+		Healthcheck:     healthcheck,
+		Domainname:      config.Domainname,
+		Entrypoint:      config.Entrypoint,
+		Env:             config.Env,
+		Hostname:        config.Hostname,
+		Image:           config.Image,
+		Labels:          config.Labels,
+		OnBuild:         config.OnBuild,
+		OpenStdin:       config.OpenStdin,
+		StdinOnce:       config.StdinOnce,
+		Tty:             config.Tty,
+		User:            config.User,
+		Volumes:         config.Volumes,
+		WorkingDir:      config.WorkingDir,
+		ExposedPorts:    exposedPorts,
+		ArgsEscaped:     config.ArgsEscaped,
+		NetworkDisabled: config.NetworkDisabled,
+		MacAddress:      config.MacAddress,
+		StopSignal:      config.StopSignal,
+		Shell:           config.Shell,
+	}
+}
@@ -0,0 +1,35 @@
+package locallayout
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/buildpacks/imgutil"
+)
+
+var (
+	_ imgutil.CopySource = &Image{}
+	_ imgutil.CopyTarget = &Image{}
+)
+
+// CopyContent exports the image from the daemon, as it was last saved.
+func (i *Image) CopyContent(ctx context.Context) (v1.Image, func(), error) {
+	store, ok := i.Store.(*Store)
+	if !ok || i.lastIdentifier == "" {
+		return nil, nil, fmt.Errorf("image %q not found in the docker daemon", i.Name())
+	}
+	return imgutil.ExportImage(ctx, store.dockerClient.ImageSave, i.lastIdentifier)
+}
+
+// SaveCopy loads image into the daemon as is, tags it with the name of the image, and makes it the content of the image.
+// As the daemon does not store the image manifest, only the image ID is reported in the result.
+func (i *Image) SaveCopy(ctx context.Context, image v1.Image) (imgutil.SaveResult, error) {
+	i.CNBImageCore.Image = image
+	results, err := i.saveAs(ctx, i.Name())
+	if err != nil {
+		return imgutil.SaveResult{}, err
+	}
+	return results[0], nil
+}
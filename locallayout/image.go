@@ -1,11 +1,10 @@
 package locallayout
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"io"
 	"strings"
-	"sync"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 
@@ -17,15 +16,20 @@ type Image struct {
 	*imgutil.CNBImageCore
 	lastIdentifier string
 	daemonOS       string
-	downloadOnce   *sync.Once
 }
 
 var _ imgutil.Image = &Image{}
+var _ imgutil.RebaseReporter = &Image{}
 
 func (i *Image) Found() bool {
 	return i.lastIdentifier != ""
 }
 
+// FoundContext is the same as Found, as the presence of the image in the daemon is determined when the image is created.
+func (i *Image) FoundContext(_ context.Context) bool {
+	return i.Found()
+}
+
 func (i *Image) Identifier() (imgutil.Identifier, error) {
 	return idStringer{
 		id: strings.TrimPrefix(i.lastIdentifier, "sha256:"),
@@ -43,6 +47,11 @@ func (i idStringer) String() string {
 // GetLayer returns an io.ReadCloser with uncompressed layer data.
 // The layer will always have data, even if that means downloading ALL the image layers from the daemon.
 func (i *Image) GetLayer(diffID string) (io.ReadCloser, error) {
+	return i.GetLayerContext(context.Background(), diffID)
+}
+
+// GetLayerContext is like GetLayer, but aborts downloading layers from the daemon when ctx is done.
+func (i *Image) GetLayerContext(ctx context.Context, diffID string) (io.ReadCloser, error) {
 	layerHash, err := v1.NewHash(diffID)
 	if err != nil {
 		return nil, err
@@ -56,57 +65,108 @@ func (i *Image) GetLayer(diffID string) (io.ReadCloser, error) {
 			return layer.Uncompressed()
 		}
 	}
-	if err = i.ensureLayers(); err != nil {
+	if err = i.ensureLayers(ctx); err != nil {
 		return nil, err
 	}
 	layer, err = i.LayerByDiffID(layerHash)
 	if err != nil {
-		return nil, fmt.Errorf("image %q does not contain layer with diff ID %q", i.Name(), layerHash.String())
+		return nil, imgutil.Errorf(imgutil.ErrLayerNotFound, "image %q does not contain layer with diff ID %q", i.Name(), layerHash.String())
 	}
 	return layer.Uncompressed()
 }
 
-func (i *Image) ensureLayers() error {
-	var err error
-	i.downloadOnce.Do(func() {
-		err = i.Store.DownloadLayersFor(i.lastIdentifier)
-	})
-	if err != nil {
+func (i *Image) ensureLayers(ctx context.Context) error {
+	if err := imgutil.ContextStore(i.Store).DownloadLayersForContext(ctx, i.lastIdentifier); err != nil {
 		return fmt.Errorf("fetching base layers: %w", err)
 	}
 	return nil
 }
 
+// Annotations is not supported, as the docker daemon does not store manifest annotations.
+func (i *Image) Annotations() (map[string]string, error) {
+	return nil, imgutil.ErrAnnotationsNotSupported
+}
+
+// SetAnnotation is not supported, as the docker daemon does not store manifest annotations.
+func (i *Image) SetAnnotation(_, _ string) error {
+	return imgutil.ErrAnnotationsNotSupported
+}
+
+// RemoveAnnotation is not supported, as the docker daemon does not store manifest annotations.
+func (i *Image) RemoveAnnotation(_ string) error {
+	return imgutil.ErrAnnotationsNotSupported
+}
+
 func (i *Image) SetOS(osVal string) error {
 	if osVal != i.daemonOS {
-		return errors.New("invalid os: must match the daemon")
+		return imgutil.Errorf(imgutil.ErrPlatformMismatch, "invalid os: must match the daemon")
 	}
 	return i.CNBImageCore.SetOS(osVal)
 }
 
 func (i *Image) Rebase(baseTopLayerDiffID string, withNewBase imgutil.Image) error {
-	if err := i.ensureLayers(); err != nil {
+	if err := i.ensureLayers(context.Background()); err != nil {
 		return err
 	}
 	return i.CNBImageCore.Rebase(baseTopLayerDiffID, withNewBase)
 }
 
+// RebaseWithReport is like Rebase, but first validates the new base as requested and reports what the rebase changed.
+func (i *Image) RebaseWithReport(baseTopLayerDiffID string, withNewBase imgutil.Image, ops ...imgutil.RebaseOption) (imgutil.RebaseReport, error) {
+	if err := i.ensureLayers(context.Background()); err != nil {
+		return imgutil.RebaseReport{}, err
+	}
+	return imgutil.RebaseWithReport(i, baseTopLayerDiffID, withNewBase, func() error {
+		return i.CNBImageCore.Rebase(baseTopLayerDiffID, withNewBase)
+	}, ops...)
+}
+
 func (i *Image) Save(additionalNames ...string) error {
-	var err error
-	i.lastIdentifier, err = i.Store.Save(i, i.Name(), additionalNames...)
-	return err
+	return i.SaveAsContext(context.Background(), i.Name(), additionalNames...)
+}
+
+func (i *Image) SaveContext(ctx context.Context, additionalNames ...string) error {
+	return i.SaveAsContext(ctx, i.Name(), additionalNames...)
 }
 
 func (i *Image) SaveAs(name string, additionalNames ...string) error {
-	var err error
-	i.lastIdentifier, err = i.Store.Save(i, name, additionalNames...)
+	return i.SaveAsContext(context.Background(), name, additionalNames...)
+}
+
+func (i *Image) SaveAsContext(ctx context.Context, name string, additionalNames ...string) error {
+	_, err := i.saveAs(ctx, name, additionalNames...)
 	return err
 }
 
+// SaveWithResult is like Save, but also returns the outcome of saving the image as each name.
+// As the daemon does not store the image manifest, only the image ID is reported for each name.
+func (i *Image) SaveWithResult(additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return i.saveAs(context.Background(), i.Name(), additionalNames...)
+}
+
+// SaveAsWithResult is like SaveAs, but also returns the outcome of saving the image as each name.
+// As the daemon does not store the image manifest, only the image ID is reported for each name.
+func (i *Image) SaveAsWithResult(name string, additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return i.saveAs(context.Background(), name, additionalNames...)
+}
+
+func (i *Image) saveAs(ctx context.Context, name string, additionalNames ...string) ([]imgutil.SaveResult, error) {
+	results, err := imgutil.ContextStore(i.Store).SaveContext(ctx, i, name, additionalNames...)
+	i.lastIdentifier = ""
+	if err == nil {
+		i.lastIdentifier = results[0].ImageID
+	}
+	return results, err
+}
+
 func (i *Image) SaveFile() (string, error) {
 	return i.Store.SaveFile(i, i.Name())
 }
 
 func (i *Image) Delete() error {
-	return i.Store.Delete(i.lastIdentifier)
+	return i.DeleteContext(context.Background())
+}
+
+func (i *Image) DeleteContext(ctx context.Context) error {
+	return imgutil.ContextStore(i.Store).DeleteContext(ctx, i.lastIdentifier)
 }
@@ -3,6 +3,7 @@ package locallayout_test
 import (
 	"archive/tar"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -432,6 +433,70 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#Annotations", func() {
+		it("returns an error", func() {
+			img, err := local.NewImage(newTestImageName(), dockerClient)
+			h.AssertNil(t, err)
+
+			_, err = img.Annotations()
+			h.AssertError(t, err, "manifest annotations are not supported for images in a docker daemon")
+		})
+	})
+
+	when("#SetAnnotation", func() {
+		it("returns an error", func() {
+			img, err := local.NewImage(newTestImageName(), dockerClient)
+			h.AssertNil(t, err)
+
+			err = img.SetAnnotation("some-key", "some-value")
+			h.AssertError(t, err, "manifest annotations are not supported for images in a docker daemon")
+		})
+	})
+
+	when("#ConfigFile", func() {
+		it("returns a copy of the config file", func() {
+			img, err := local.NewImage(newTestImageName(), dockerClient)
+			h.AssertNil(t, err)
+
+			configFile, err := img.ConfigFile()
+			h.AssertNil(t, err)
+			configFile.Config.Labels = map[string]string{"some-key": "some-value"}
+
+			label, err := img.Label("some-key")
+			h.AssertNil(t, err)
+			h.AssertEq(t, label, "")
+		})
+	})
+
+	when("#MutateConfigFile", func() {
+		it("errors when the layers or the number of history entries are changed", func() {
+			img, err := local.NewImage(newTestImageName(), dockerClient)
+			h.AssertNil(t, err)
+			layerPath, err := h.CreateSingleFileLayerTar("/some-file.txt", "some-content", daemonOS)
+			h.AssertNil(t, err)
+			defer os.Remove(layerPath)
+			h.AssertNil(t, img.AddLayer(layerPath))
+
+			err = img.MutateConfigFile(func(c *v1.ConfigFile) {
+				c.Config.User = "some-user"
+				c.RootFS.DiffIDs = c.RootFS.DiffIDs[:len(c.RootFS.DiffIDs)-1]
+			})
+			h.AssertEq(t, errors.Is(err, imgutil.ErrNotImplemented), true)
+
+			err = img.MutateConfigFile(func(c *v1.ConfigFile) {
+				c.Config.User = "some-user"
+				c.History = append(c.History, v1.History{CreatedBy: "some-history"})
+			})
+			h.AssertError(t, err, "invalid history")
+
+			// the image is left as is
+			configFile, err := img.ConfigFile()
+			h.AssertNil(t, err)
+			h.AssertEq(t, configFile.Config.User, "")
+			h.AssertEq(t, len(configFile.RootFS.DiffIDs), 1)
+		})
+	})
+
 	when("#Labels", func() {
 		when("image exists with labels", func() {
 			var repoName = newTestImageName()
@@ -1577,6 +1642,25 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 					}
 					h.AssertEq(t, string(contents), "file-contents")
 				})
+
+				when("downloading the layers was cancelled before", func() {
+					it("downloads them again", func() {
+						img, err := local.NewImage(repoName, dockerClient, local.FromBaseImage(repoName))
+						h.AssertNil(t, err)
+
+						topLayer, err := img.TopLayer()
+						h.AssertNil(t, err)
+
+						ctx, cancel := context.WithCancel(context.Background())
+						cancel()
+						_, err = img.GetLayerContext(ctx, topLayer)
+						h.AssertError(t, err, "fetching base layers")
+
+						r, err := img.GetLayer(topLayer)
+						h.AssertNil(t, err)
+						h.AssertNil(t, r.Close())
+					})
+				})
 			})
 
 			when("the layer does not exist", func() {
@@ -3,7 +3,6 @@ package locallayout
 import (
 	"context"
 	"fmt"
-	"sync"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/image"
@@ -33,7 +32,7 @@ func NewImage(repoName string, dockerClient DockerClient, ops ...func(*imgutil.I
 
 	var (
 		baseIdentifier string
-		store          imgutil.ImageStore = &Store{dockerClient: dockerClient}
+		store          = &Store{dockerClient: dockerClient, progress: options.Progress}
 	)
 	baseImage, err := processBaseImageOption(options.BaseImageRepoName, dockerClient)
 	if err != nil {
@@ -42,7 +41,7 @@ func NewImage(repoName string, dockerClient DockerClient, ops ...func(*imgutil.I
 	if baseImage != nil {
 		options.BaseImage = baseImage
 		baseIdentifier = baseImage.identifier
-		store = baseImage.store
+		baseImage.store = store // share the store, so that layers downloaded for the base image are available to the image
 	}
 
 	cnbImage, err := imgutil.NewCNBImage(repoName, store, *options)
@@ -54,7 +53,6 @@ func NewImage(repoName string, dockerClient DockerClient, ops ...func(*imgutil.I
 		CNBImageCore:   cnbImage,
 		lastIdentifier: baseIdentifier,
 		daemonOS:       options.Platform.OS,
-		downloadOnce:   &sync.Once{},
 	}, nil
 }
 
@@ -68,7 +66,7 @@ func processDefaultPlatformOption(requestedPlatform imgutil.Platform, dockerClie
 	}
 	if requestedPlatform.OS != "" && requestedPlatform.OS != dockerPlatform.OS {
 		return imgutil.Platform{},
-			fmt.Errorf("invalid os: platform os %q must match the daemon os %q", requestedPlatform.OS, dockerPlatform.OS)
+			imgutil.Errorf(imgutil.ErrPlatformMismatch, "invalid os: platform os %q must match the daemon os %q", requestedPlatform.OS, dockerPlatform.OS)
 	}
 	return requestedPlatform, nil
 }
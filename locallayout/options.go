@@ -49,3 +49,9 @@ func WithMediaTypes(m imgutil.MediaTypes) func(*imgutil.ImageOptions) {
 		o.MediaTypes = m
 	}
 }
+
+func WithProgress(handler imgutil.ProgressHandler) func(*imgutil.ImageOptions) {
+	return func(o *imgutil.ImageOptions) {
+		o.Progress = handler
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/image"
@@ -30,6 +31,10 @@ type Store struct {
 	dockerClient DockerClient
 	// optional
 	onDiskLayers []v1.Layer
+	progress     imgutil.ProgressHandler
+
+	downloadMu sync.Mutex
+	downloaded map[string]bool // identifiers of the images whose layers are in onDiskLayers
 }
 
 // DockerClient is subset of client.CommonAPIClient required by this package.
@@ -44,62 +49,84 @@ type DockerClient interface {
 	ServerVersion(ctx context.Context) (types.Version, error)
 }
 
-var _ imgutil.ImageStore = &Store{}
+var _ imgutil.ContextImageStore = &Store{}
 
 // images
 
 func (s *Store) Contains(identifier string) bool {
-	_, _, err := s.dockerClient.ImageInspectWithRaw(context.Background(), identifier)
+	return s.ContainsContext(context.Background(), identifier)
+}
+
+func (s *Store) ContainsContext(ctx context.Context, identifier string) bool {
+	_, _, err := s.dockerClient.ImageInspectWithRaw(ctx, identifier)
 	return err == nil
 }
 
 func (s *Store) Delete(identifier string) error {
-	if !s.Contains(identifier) {
+	return s.DeleteContext(context.Background(), identifier)
+}
+
+func (s *Store) DeleteContext(ctx context.Context, identifier string) error {
+	if !s.ContainsContext(ctx, identifier) {
 		return nil
 	}
 	options := types.ImageRemoveOptions{
 		Force:         true,
 		PruneChildren: true,
 	}
-	_, err := s.dockerClient.ImageRemove(context.Background(), identifier, options)
+	_, err := s.dockerClient.ImageRemove(ctx, identifier, options)
 	return err
 }
 
+// Save loads the image into the daemon and tags it with each name, and returns the image ID.
 func (s *Store) Save(image imgutil.IdentifiableV1Image, withName string, withAdditionalNames ...string) (string, error) {
+	results, err := s.SaveContext(context.Background(), image, withName, withAdditionalNames...)
+	if err != nil {
+		return "", err
+	}
+	return results[0].ImageID, nil
+}
+
+// SaveContext loads the image into the daemon and tags it with each name, returning the outcome for each name.
+// As the daemon does not store the image manifest, only the image ID is reported in the results.
+func (s *Store) SaveContext(ctx context.Context, image imgutil.IdentifiableV1Image, withName string, withAdditionalNames ...string) ([]imgutil.SaveResult, error) {
 	withName = tryNormalizing(withName)
+	allNames := append([]string{withName}, withAdditionalNames...)
 
 	// save
-	inspect, err := s.doSave(image, withName)
+	inspect, err := s.doSave(ctx, image, withName)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		identifier, err := image.Identifier()
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		if err = s.DownloadLayersFor(identifier.String()); err != nil {
-			return "", err
+		if err = s.DownloadLayersForContext(ctx, identifier.String()); err != nil {
+			return nil, err
 		}
-		inspect, err = s.doSave(image, withName)
+		inspect, err = s.doSave(ctx, image, withName)
 		if err != nil {
-			saveErr := imgutil.SaveError{}
-			for _, n := range append([]string{withName}, withAdditionalNames...) {
-				saveErr.Errors = append(saveErr.Errors, imgutil.SaveDiagnostic{ImageName: n, Cause: err})
+			var results []imgutil.SaveResult
+			for _, n := range allNames {
+				results = append(results, imgutil.SaveResult{Name: n, Err: err})
 			}
-			return "", saveErr
+			return results, imgutil.SaveErrorFrom(results)
 		}
 	}
 
 	// tag additional names
-	var errs []imgutil.SaveDiagnostic
-	for _, n := range append([]string{withName}, withAdditionalNames...) {
-		if err = s.dockerClient.ImageTag(context.Background(), inspect.ID, n); err != nil {
-			errs = append(errs, imgutil.SaveDiagnostic{ImageName: n, Cause: err})
+	var results []imgutil.SaveResult
+	for _, n := range allNames {
+		result := imgutil.SaveResult{Name: n, ImageID: inspect.ID}
+		if err = s.dockerClient.ImageTag(ctx, inspect.ID, n); err != nil {
+			result = imgutil.SaveResult{Name: n, Err: err}
 		}
-	}
-	if len(errs) > 0 {
-		return "", imgutil.SaveError{Errors: errs}
+		results = append(results, result)
 	}
 
-	return inspect.ID, nil
+	return results, imgutil.SaveErrorFrom(results)
 }
 
 func tryNormalizing(name string) string {
@@ -111,18 +138,18 @@ func tryNormalizing(name string) string {
 	return t.Name() // returns valid 'name:tag' appending 'latest', if missing tag
 }
 
-func (s *Store) doSave(image v1.Image, withName string) (types.ImageInspect, error) {
-	ctx := context.Background()
-	done := make(chan error)
+func (s *Store) doSave(ctx context.Context, image v1.Image, withName string) (types.ImageInspect, error) {
+	done := make(chan error, 1)
 
-	var err error
 	pr, pw := io.Pipe()
 	defer pw.Close()
+	defer imgutil.CloseOnDone(ctx, pr)()
 
 	go func() {
-		var res types.ImageLoadResponse
-		res, err = s.dockerClient.ImageLoad(ctx, pr, true)
+		res, err := s.dockerClient.ImageLoad(ctx, pr, true)
 		if err != nil {
+			// unblock the tar producer, which would otherwise wait forever on the pipe
+			pr.CloseWithError(err)
 			done <- err
 			return
 		}
@@ -144,17 +171,23 @@ func (s *Store) doSave(image v1.Image, withName string) (types.ImageInspect, err
 	tw := tar.NewWriter(pw)
 	defer tw.Close()
 
-	if err = s.addImageToTar(tw, image, withName); err != nil {
+	if err := s.addImageToTar(tw, image, withName, imgutil.PhaseLoading); err != nil {
+		pw.CloseWithError(err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return types.ImageInspect{}, ctxErr
+		}
 		return types.ImageInspect{}, err
 	}
 	tw.Close()
 	pw.Close()
-	err = <-done
-	if err != nil {
+	if err := <-done; err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return types.ImageInspect{}, ctxErr
+		}
 		return types.ImageInspect{}, fmt.Errorf("loading image %q. first error: %w", withName, err)
 	}
 
-	inspect, _, err := s.dockerClient.ImageInspectWithRaw(context.Background(), withName)
+	inspect, _, err := s.dockerClient.ImageInspectWithRaw(ctx, withName)
 	if err != nil {
 		if client.IsErrNotFound(err) {
 			return types.ImageInspect{}, fmt.Errorf("saving image %q: %w", withName, err)
@@ -164,7 +197,7 @@ func (s *Store) doSave(image v1.Image, withName string) (types.ImageInspect, err
 	return inspect, nil
 }
 
-func (s *Store) addImageToTar(tw *tar.Writer, image v1.Image, withName string) error {
+func (s *Store) addImageToTar(tw *tar.Writer, image v1.Image, withName string, phase imgutil.ProgressPhase) error {
 	rawConfigFile, err := image.RawConfigFile()
 	if err != nil {
 		return err
@@ -195,7 +228,7 @@ func (s *Store) addImageToTar(tw *tar.Writer, image v1.Image, withName string) e
 				return err
 			}
 		} else {
-			layerName, err = s.addLayerToTar(tw, layer)
+			layerName, err = s.addLayerToTar(tw, layer, phase)
 			if err != nil {
 				return err
 			}
@@ -216,7 +249,7 @@ func (s *Store) addImageToTar(tw *tar.Writer, image v1.Image, withName string) e
 	return addTextToTar(tw, manifestJSON, "manifest.json")
 }
 
-func (s *Store) addLayerToTar(tw *tar.Writer, layer v1.Layer) (string, error) {
+func (s *Store) addLayerToTar(tw *tar.Writer, layer v1.Layer, phase imgutil.ProgressPhase) (string, error) {
 	layerDiffID, err := layer.DiffID()
 	if err != nil {
 		return "", err
@@ -237,7 +270,7 @@ func (s *Store) addLayerToTar(tw *tar.Writer, layer v1.Layer) (string, error) {
 		return "", err
 	}
 	defer layerReader.Close()
-	if _, err = io.Copy(tw, layerReader); err != nil {
+	if _, err = io.Copy(tw, imgutil.NewProgressReader(layerReader, layerDiffID, uncompressedSize, phase, s.progress)); err != nil {
 		return "", err
 	}
 
@@ -293,6 +326,10 @@ func ensureReaderClosed(r io.ReadCloser) error {
 }
 
 func (s *Store) SaveFile(image imgutil.IdentifiableV1Image, withName string) (string, error) {
+	return s.SaveFileContext(context.Background(), image, withName)
+}
+
+func (s *Store) SaveFileContext(ctx context.Context, image imgutil.IdentifiableV1Image, withName string) (string, error) {
 	withName = tryNormalizing(withName)
 
 	f, err := os.CreateTemp("", "imgutil.local.image.export.*.tar")
@@ -314,12 +351,13 @@ func (s *Store) SaveFile(image imgutil.IdentifiableV1Image, withName string) (st
 	if err != nil {
 		return "", err
 	}
-	if err = s.DownloadLayersFor(identifier.String()); err != nil {
+	if err = s.DownloadLayersForContext(ctx, identifier.String()); err != nil {
 		return "", err
 	}
 
-	errs, _ := errgroup.WithContext(context.Background())
+	errs, groupCtx := errgroup.WithContext(ctx)
 	pr, pw := io.Pipe()
+	defer imgutil.CloseOnDone(groupCtx, pr)()
 
 	// File writer
 	errs.Go(func() error {
@@ -335,10 +373,13 @@ func (s *Store) SaveFile(image imgutil.IdentifiableV1Image, withName string) (st
 		tw := tar.NewWriter(pw)
 		defer tw.Close()
 
-		return s.addImageToTar(tw, image, withName)
+		return s.addImageToTar(tw, image, withName, imgutil.PhaseWriting)
 	})
 
 	err = errs.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
 	if err != nil {
 		return "", err
 	}
@@ -348,19 +389,33 @@ func (s *Store) SaveFile(image imgutil.IdentifiableV1Image, withName string) (st
 // layers
 
 func (s *Store) DownloadLayersFor(identifier string) error {
-	layers, err := downloadLayersFor(identifier, s.dockerClient)
+	return s.DownloadLayersForContext(context.Background(), identifier)
+}
+
+// DownloadLayersForContext downloads the layers of the image with the given identifier from the daemon,
+// unless they were already downloaded. If the download fails or ctx is done, a later call tries again.
+func (s *Store) DownloadLayersForContext(ctx context.Context, identifier string) error {
+	s.downloadMu.Lock()
+	defer s.downloadMu.Unlock()
+	if s.downloaded[identifier] {
+		return nil
+	}
+	layers, err := downloadLayersFor(ctx, identifier, s.dockerClient)
 	if err != nil {
 		return err
 	}
 	s.onDiskLayers = append(s.onDiskLayers, layers...)
+	if s.downloaded == nil {
+		s.downloaded = make(map[string]bool)
+	}
+	s.downloaded[identifier] = true
 	return nil
 }
 
-func downloadLayersFor(identifier string, dockerClient DockerClient) ([]v1.Layer, error) {
+func downloadLayersFor(ctx context.Context, identifier string, dockerClient DockerClient) ([]v1.Layer, error) {
 	if identifier == "" {
 		return nil, nil
 	}
-	ctx := context.Background()
 
 	imageReader, err := dockerClient.ImageSave(ctx, []string{identifier})
 	if err != nil {
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types"
@@ -32,7 +31,6 @@ type v1ImageFacade struct {
 	// for downloading layers from the daemon as needed
 	store                  imgutil.ImageStore
 	downloadLayersOnAccess bool // set to true to downloading ALL the image layers from the daemon when LayerByDiffID is called
-	downloadOnce           *sync.Once
 	identifier             string
 }
 
@@ -53,15 +51,11 @@ func (i *v1ImageFacade) LayerByDiffID(h v1.Hash) (v1.Layer, error) {
 	if layer := findLayer(h, i.emptyLayers); layer != nil {
 		return layer, nil
 	}
-	return nil, fmt.Errorf("failed to find layer with diff ID %q", h.String())
+	return nil, imgutil.Errorf(imgutil.ErrLayerNotFound, "failed to find layer with diff ID %q", h.String())
 }
 
 func (i *v1ImageFacade) ensureLayers() error {
-	var err error
-	i.downloadOnce.Do(func() {
-		err = i.store.DownloadLayersFor(i.identifier)
-	})
-	if err != nil {
+	if err := i.store.DownloadLayersFor(i.identifier); err != nil {
 		return fmt.Errorf("fetching base layers: %w", err)
 	}
 	return nil
@@ -107,7 +101,6 @@ func newV1ImageFacadeFromInspect(dockerInspect types.ImageInspect, history []ima
 		emptyLayers:            newEmptyLayerListFrom(configFile),
 		store:                  &Store{dockerClient: dockerClient},
 		downloadLayersOnAccess: downloadLayersOnAccess,
-		downloadOnce:           &sync.Once{},
 		identifier:             dockerInspect.ID,
 	}, nil
 }
@@ -79,6 +79,8 @@ func emptyV1(withPlatform Platform) (v1.Image, error) {
 		History:      []v1.History{},
 		OS:           withPlatform.OS,
 		OSVersion:    withPlatform.OSVersion,
+		Variant:      withPlatform.Variant,
+		OSFeatures:   withPlatform.OSFeatures(),
 		RootFS: v1.RootFS{
 			Type:    "layers",
 			DiffIDs: []v1.Hash{},
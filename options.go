@@ -14,6 +14,7 @@ type ImageOptions struct {
 	PreserveHistory       bool
 	PreviousImageRepoName string
 	MediaTypes            MediaTypes
+	Progress              ProgressHandler
 
 	// These options are specified in each implementation's image constructor
 	BaseImage     v1.Image
@@ -0,0 +1,150 @@
+package imgutil
+
+import (
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// ProgressPhase describes what is happening to a layer while an image is being saved.
+type ProgressPhase string
+
+const (
+	// PhaseUploading is reported while the layer is being pushed to a registry.
+	PhaseUploading ProgressPhase = "uploading"
+	// PhaseExists is reported when the registry already has the layer, so it is not pushed.
+	PhaseExists ProgressPhase = "exists"
+	// PhaseMounted is reported when the registry mounted the layer from another repository, so it is not pushed.
+	PhaseMounted ProgressPhase = "mounted"
+	// PhaseLoading is reported while the layer is being streamed to a docker daemon.
+	PhaseLoading ProgressPhase = "loading into daemon"
+	// PhaseWriting is reported while the layer is being written to disk, e.g. to an OCI layout or an archive.
+	PhaseWriting ProgressPhase = "writing"
+)
+
+// Progress is reported for a single layer while an image is being saved.
+type Progress struct {
+	// Digest identifies the layer. It is the digest of the compressed layer when the compressed contents are written
+	// (registry pushes, OCI layouts), and the diff ID when the uncompressed contents are written (daemon loads).
+	// It is empty for the updates of a registry push as a whole, which report the bytes of all the blobs so far.
+	Digest v1.Hash
+	// Complete is the number of bytes of the layer written so far.
+	Complete int64
+	// Total is the size of the layer in bytes, or -1 if it is not known.
+	Total int64
+	Phase ProgressPhase
+}
+
+// ProgressHandler receives progress updates while an image is being saved.
+// Handlers may be called concurrently for different layers and should return quickly.
+type ProgressHandler func(Progress)
+
+// NewProgressReader returns a reader that reports progress for the layer identified by digest as r is read.
+// If handler is nil, r is returned as is.
+func NewProgressReader(r io.Reader, digest v1.Hash, total int64, phase ProgressPhase, handler ProgressHandler) io.Reader {
+	if handler == nil {
+		return r
+	}
+	return &progressReader{Reader: r, update: Progress{Digest: digest, Total: total, Phase: phase}, handler: handler}
+}
+
+type progressReader struct {
+	io.Reader
+	update  Progress
+	handler ProgressHandler
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.update.Complete += int64(n)
+		r.handler(r.update)
+	}
+	return n, err
+}
+
+// ProgressReportingImage returns a v1.Image whose layers report progress with the given phase
+// as their compressed contents are read, e.g. when the image is pushed or written to disk.
+// If handler is nil, image is returned as is.
+func ProgressReportingImage(image v1.Image, phase ProgressPhase, handler ProgressHandler) v1.Image {
+	if handler == nil {
+		return image
+	}
+	return &progressImage{Image: image, phase: phase, handler: handler}
+}
+
+type progressImage struct {
+	v1.Image
+	phase   ProgressPhase
+	handler ProgressHandler
+}
+
+func (i *progressImage) Layers() ([]v1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	wrapped := make([]v1.Layer, len(layers))
+	for idx, layer := range layers {
+		wrapped[idx] = i.wrap(layer)
+	}
+	return wrapped, nil
+}
+
+func (i *progressImage) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	layer, err := i.Image.LayerByDigest(h)
+	if err != nil {
+		return nil, err
+	}
+	return i.wrap(layer), nil
+}
+
+func (i *progressImage) LayerByDiffID(h v1.Hash) (v1.Layer, error) {
+	layer, err := i.Image.LayerByDiffID(h)
+	if err != nil {
+		return nil, err
+	}
+	return i.wrap(layer), nil
+}
+
+func (i *progressImage) wrap(layer v1.Layer) v1.Layer {
+	if mountable, ok := layer.(*remote.MountableLayer); ok {
+		// keep the layer mountable, so that registries can still mount it from its original repository
+		return &remote.MountableLayer{
+			Layer:     &progressLayer{Layer: mountable.Layer, phase: i.phase, handler: i.handler},
+			Reference: mountable.Reference,
+		}
+	}
+	return &progressLayer{Layer: layer, phase: i.phase, handler: i.handler}
+}
+
+type progressLayer struct {
+	v1.Layer
+	phase   ProgressPhase
+	handler ProgressHandler
+}
+
+func (l *progressLayer) Compressed() (io.ReadCloser, error) {
+	digest, err := l.Layer.Digest()
+	if err != nil {
+		return nil, err
+	}
+	size, err := l.Layer.Size()
+	if err != nil {
+		size = -1
+	}
+	rc, err := l.Layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	return &readCloser{
+		Reader: NewProgressReader(rc, digest, size, l.phase, l.handler),
+		Closer: rc,
+	}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
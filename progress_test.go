@@ -0,0 +1,127 @@
+package imgutil_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/imgutil"
+	h "github.com/buildpacks/imgutil/testhelpers"
+)
+
+func TestProgress(t *testing.T) {
+	spec.Run(t, "Progress", testProgress, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testProgress(t *testing.T, when spec.G, it spec.S) {
+	var updates []imgutil.Progress
+	handler := func(p imgutil.Progress) {
+		updates = append(updates, p)
+	}
+
+	it.Before(func() {
+		updates = nil
+	})
+
+	when("#NewProgressReader", func() {
+		it("reports the bytes read so far", func() {
+			digest := v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("a", 64)}
+			r := imgutil.NewProgressReader(strings.NewReader("some-content"), digest, 12, imgutil.PhaseWriting, handler)
+
+			buf := make([]byte, 5)
+			for {
+				if _, err := r.Read(buf); err == io.EOF {
+					break
+				}
+			}
+
+			h.AssertEq(t, updates, []imgutil.Progress{
+				{Digest: digest, Complete: 5, Total: 12, Phase: imgutil.PhaseWriting},
+				{Digest: digest, Complete: 10, Total: 12, Phase: imgutil.PhaseWriting},
+				{Digest: digest, Complete: 12, Total: 12, Phase: imgutil.PhaseWriting},
+			})
+		})
+
+		it("returns the reader as is without a handler", func() {
+			r := strings.NewReader("some-content")
+			h.AssertEq(t, imgutil.NewProgressReader(r, v1.Hash{}, 12, imgutil.PhaseWriting, nil) == io.Reader(r), true)
+		})
+	})
+
+	when("#ProgressReportingImage", func() {
+		var image v1.Image
+
+		it.Before(func() {
+			var err error
+			image, err = random.Image(1024, 2)
+			h.AssertNil(t, err)
+		})
+
+		it("reports the progress of each layer as its compressed contents are read", func() {
+			layers, err := imgutil.ProgressReportingImage(image, imgutil.PhaseUploading, handler).Layers()
+			h.AssertNil(t, err)
+
+			for _, layer := range layers {
+				updates = nil
+				rc, err := layer.Compressed()
+				h.AssertNil(t, err)
+				_, err = io.Copy(io.Discard, rc)
+				h.AssertNil(t, err)
+				h.AssertNil(t, rc.Close())
+
+				digest, err := layer.Digest()
+				h.AssertNil(t, err)
+				size, err := layer.Size()
+				h.AssertNil(t, err)
+				h.AssertTrue(t, func() bool { return len(updates) > 0 })
+				for _, update := range updates {
+					h.AssertEq(t, update.Digest, digest)
+					h.AssertEq(t, update.Total, size)
+					h.AssertEq(t, update.Phase, imgutil.PhaseUploading)
+				}
+				h.AssertEq(t, updates[len(updates)-1].Complete, size)
+			}
+		})
+
+		it("keeps mountable layers mountable", func() {
+			ref, err := name.ParseReference("registry.example.com/some-repo")
+			h.AssertNil(t, err)
+
+			layers, err := imgutil.ProgressReportingImage(&mountableImage{Image: image, ref: ref}, imgutil.PhaseUploading, handler).Layers()
+			h.AssertNil(t, err)
+			for _, layer := range layers {
+				mountable, ok := layer.(*remote.MountableLayer)
+				h.AssertEq(t, ok, true)
+				h.AssertEq(t, mountable.Reference.String(), ref.String())
+			}
+		})
+
+		it("returns the image as is without a handler", func() {
+			h.AssertEq(t, imgutil.ProgressReportingImage(image, imgutil.PhaseUploading, nil) == image, true)
+		})
+	})
+}
+
+// mountableImage is an image whose layers are mountable from ref, as are the layers of images read from a registry.
+type mountableImage struct {
+	v1.Image
+	ref name.Reference
+}
+
+func (i *mountableImage) Layers() ([]v1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	for idx, layer := range layers {
+		layers[idx] = &remote.MountableLayer{Layer: layer, Reference: i.ref}
+	}
+	return layers, nil
+}
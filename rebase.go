@@ -0,0 +1,221 @@
+package imgutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// NewBaseImage returns the content of newBase, to rebase images of any kind onto it.
+// The layers of the new base are only read when the rebased image is saved, e.g. fetched from the registry of a remote
+// base, or read with GetLayer from an image in a docker daemon, which keeps the layers it downloads from the daemon.
+func NewBaseImage(newBase Image) (v1.Image, error) {
+	if _, ok := newBase.(CopySource); ok {
+		configFile, err := newBase.ConfigFile()
+		if err != nil {
+			return nil, fmt.Errorf("reading new base image %q: %w", newBase.Name(), err)
+		}
+		rawConfig, err := json.Marshal(configFile)
+		if err != nil {
+			return nil, err
+		}
+		return partial.UncompressedToImage(&layerReadingImage{image: newBase, rawConfig: rawConfig})
+	}
+	image := newBase.UnderlyingImage()
+	if image == nil {
+		return nil, fmt.Errorf("missing new base image %q", newBase.Name())
+	}
+	return image, nil
+}
+
+// layerReadingImage is an image with the config of an Image, whose layers are read with its GetLayer method.
+type layerReadingImage struct {
+	image     Image
+	rawConfig []byte
+}
+
+func (i *layerReadingImage) RawConfigFile() ([]byte, error) {
+	return i.rawConfig, nil
+}
+
+func (i *layerReadingImage) MediaType() (types.MediaType, error) {
+	return types.DockerManifestSchema2, nil
+}
+
+func (i *layerReadingImage) LayerByDiffID(diffID v1.Hash) (partial.UncompressedLayer, error) {
+	return &layerReadingLayer{image: i.image, diffID: diffID}, nil
+}
+
+type layerReadingLayer struct {
+	image  Image
+	diffID v1.Hash
+}
+
+func (l *layerReadingLayer) DiffID() (v1.Hash, error) {
+	return l.diffID, nil
+}
+
+func (l *layerReadingLayer) Uncompressed() (io.ReadCloser, error) {
+	return l.image.GetLayer(l.diffID.String())
+}
+
+func (l *layerReadingLayer) MediaType() (types.MediaType, error) {
+	return types.DockerLayer, nil
+}
+
+// RebaseReporter is implemented by images that can validate a new base before rebasing onto it,
+// and report what the rebase changed.
+type RebaseReporter interface {
+	// RebaseWithReport is like Rebase, but first validates the new base as requested and reports what the rebase changed.
+	RebaseWithReport(baseTopLayerDiffID string, newBase Image, ops ...RebaseOption) (RebaseReport, error)
+}
+
+// RebaseOption configures how an image is rebased by RebaseReporter.RebaseWithReport.
+type RebaseOption func(*RebaseOptions)
+
+// RebaseOptions are the checks that a new base image must pass before an image is rebased onto it.
+type RebaseOptions struct {
+	// ValidatePlatform requires the new base to have the OS, architecture and variant of the image,
+	// and, for Windows images, an OS version with the same major, minor and build numbers.
+	ValidatePlatform bool
+	// ValidateLabels are the labels that the new base must have with the same values as the image,
+	// e.g. the labels identifying the stack that the image was built for.
+	ValidateLabels []string
+}
+
+// WithPlatformValidation requires the new base to be for the platform of the image.
+func WithPlatformValidation() RebaseOption {
+	return func(o *RebaseOptions) {
+		o.ValidatePlatform = true
+	}
+}
+
+// WithLabelValidation requires the new base to have the given labels with the same values as the image.
+func WithLabelValidation(keys ...string) RebaseOption {
+	return func(o *RebaseOptions) {
+		o.ValidateLabels = append(o.ValidateLabels, keys...)
+	}
+}
+
+// RebaseReport describes what rebasing an image changed.
+type RebaseReport struct {
+	// RemovedBaseLayers are the diff IDs of the layers of the old base, bottom first.
+	RemovedBaseLayers []v1.Hash
+	// AddedBaseLayers are the diff IDs of the layers of the new base, bottom first.
+	AddedBaseLayers []v1.Hash
+	// ChangedConfig are the fields of the image config that were set to other values by the new base.
+	ChangedConfig []ConfigChange
+}
+
+// ConfigChange is a field of the image config that was changed.
+type ConfigChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// RebaseWithReport is a helper for implementations of RebaseReporter.
+// It validates newBase as requested by ops, and rebases image with rebase, which must set the OS, architecture and
+// OS version of the image to those of newBase. The report is read from the config files of image and newBase before
+// image is rebased, so that a failed read does not leave the image rebased without a report.
+func RebaseWithReport(image Image, baseTopLayerDiffID string, newBase Image, rebase func() error, ops ...RebaseOption) (RebaseReport, error) {
+	options := &RebaseOptions{}
+	for _, op := range ops {
+		op(options)
+	}
+	configFile, err := image.ConfigFile()
+	if err != nil {
+		return RebaseReport{}, err
+	}
+	newBaseConfigFile, err := newBase.ConfigFile()
+	if err != nil {
+		return RebaseReport{}, fmt.Errorf("reading new base image %q: %w", newBase.Name(), err)
+	}
+	if err := validateNewBase(configFile, newBaseConfigFile, newBase.Name(), options); err != nil {
+		return RebaseReport{}, err
+	}
+
+	var report RebaseReport
+	for idx, diffID := range configFile.RootFS.DiffIDs {
+		if diffID.String() == baseTopLayerDiffID {
+			report.RemovedBaseLayers = append([]v1.Hash(nil), configFile.RootFS.DiffIDs[:idx+1]...)
+			break
+		}
+	}
+	report.AddedBaseLayers = append([]v1.Hash(nil), newBaseConfigFile.RootFS.DiffIDs...)
+	for _, change := range []ConfigChange{
+		{Field: "OS", Old: configFile.OS, New: newBaseConfigFile.OS},
+		{Field: "Architecture", Old: configFile.Architecture, New: newBaseConfigFile.Architecture},
+		{Field: "OSVersion", Old: configFile.OSVersion, New: newBaseConfigFile.OSVersion},
+	} {
+		if change.New != change.Old {
+			report.ChangedConfig = append(report.ChangedConfig, change)
+		}
+	}
+
+	if err := rebase(); err != nil {
+		return RebaseReport{}, err
+	}
+	return report, nil
+}
+
+func validateNewBase(configFile, newBaseConfigFile *v1.ConfigFile, newBaseName string, options *RebaseOptions) error {
+	if options.ValidatePlatform {
+		if err := validatePlatform(configFile, newBaseConfigFile, newBaseName); err != nil {
+			return err
+		}
+	}
+	for _, key := range options.ValidateLabels {
+		want := configFile.Config.Labels[key]
+		got := newBaseConfigFile.Config.Labels[key]
+		if got != want {
+			return Errorf(ErrIncompatibleBase, "new base image %q has label %q set to %q, expected %q", newBaseName, key, got, want)
+		}
+	}
+	return nil
+}
+
+func validatePlatform(configFile, newBaseConfigFile *v1.ConfigFile, newBaseName string) error {
+	fields := []struct {
+		field     string
+		want, got string
+	}{
+		{"OS", configFile.OS, newBaseConfigFile.OS},
+		{"architecture", configFile.Architecture, newBaseConfigFile.Architecture},
+		{"variant", configFile.Variant, newBaseConfigFile.Variant},
+	}
+	for _, field := range fields {
+		if field.field == "variant" && (field.want == "" || field.got == "") {
+			// the variant is often left unset, e.g. for images of architectures with a single variant
+			continue
+		}
+		if field.got != field.want {
+			return Errorf(ErrIncompatibleBase, "new base image %q has %s %q, expected %q", newBaseName, field.field, field.got, field.want)
+		}
+	}
+
+	if configFile.OS != "windows" {
+		return nil
+	}
+	want, got := configFile.OSVersion, newBaseConfigFile.OSVersion
+	// Windows containers require the build of the host, i.e. the first three parts of the version,
+	// while the revision may differ
+	if want != "" && windowsBuild(got) != windowsBuild(want) {
+		return Errorf(ErrIncompatibleBase, "new base image %q has OS version %q, expected a version of build %q", newBaseName, got, windowsBuild(want))
+	}
+	return nil
+}
+
+// windowsBuild returns the major, minor and build numbers of a Windows version such as "10.0.17763.1234".
+func windowsBuild(version string) string {
+	parts := strings.SplitN(version, ".", 4)
+	if len(parts) > 3 {
+		parts = parts[:3]
+	}
+	return strings.Join(parts, ".")
+}
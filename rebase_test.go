@@ -0,0 +1,138 @@
+package imgutil_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/fakes"
+	h "github.com/buildpacks/imgutil/testhelpers"
+)
+
+func TestRebase(t *testing.T) {
+	spec.Run(t, "Rebase", testRebase, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testRebase(t *testing.T, when spec.G, it spec.S) {
+	var (
+		image, newBase *fakes.Image
+		rebased        bool
+	)
+
+	it.Before(func() {
+		image = fakes.NewImage("some-image", "", nil)
+		newBase = fakes.NewImage("some-new-base", "", nil)
+		rebased = false
+	})
+
+	rebaseWithReport := func(ops ...imgutil.RebaseOption) (imgutil.RebaseReport, error) {
+		return imgutil.RebaseWithReport(image, "some-top-layer", newBase, func() error {
+			rebased = true
+			return nil
+		}, ops...)
+	}
+
+	when("#RebaseWithReport", func() {
+		it("rebases the image and reports the config fields changed by the new base", func() {
+			h.AssertNil(t, newBase.SetOSVersion("1.2.3"))
+
+			report, err := rebaseWithReport()
+			h.AssertNil(t, err)
+			h.AssertEq(t, rebased, true)
+			h.AssertEq(t, report.ChangedConfig, []imgutil.ConfigChange{{Field: "OSVersion", Old: "", New: "1.2.3"}})
+		})
+
+		it("does not validate the new base unless requested", func() {
+			h.AssertNil(t, newBase.SetArchitecture("arm64"))
+
+			_, err := rebaseWithReport()
+			h.AssertNil(t, err)
+			h.AssertEq(t, rebased, true)
+		})
+
+		when("the platform is validated", func() {
+			it("fails when the new base is for another architecture", func() {
+				h.AssertNil(t, newBase.SetArchitecture("arm64"))
+
+				_, err := rebaseWithReport(imgutil.WithPlatformValidation())
+				h.AssertError(t, err, `new base image "some-new-base" has architecture "arm64", expected "amd64"`)
+				h.AssertEq(t, errors.Is(err, imgutil.ErrIncompatibleBase), true)
+				h.AssertEq(t, rebased, false)
+			})
+
+			it("fails when the variants differ", func() {
+				h.AssertNil(t, image.SetVariant("v7"))
+				h.AssertNil(t, newBase.SetVariant("v8"))
+
+				_, err := rebaseWithReport(imgutil.WithPlatformValidation())
+				h.AssertEq(t, errors.Is(err, imgutil.ErrIncompatibleBase), true)
+				h.AssertEq(t, rebased, false)
+			})
+
+			it("ignores the variant when it is not set on both images", func() {
+				h.AssertNil(t, image.SetVariant("v7"))
+
+				_, err := rebaseWithReport(imgutil.WithPlatformValidation())
+				h.AssertNil(t, err)
+				h.AssertEq(t, rebased, true)
+			})
+
+			when("the image is for windows", func() {
+				it.Before(func() {
+					h.AssertNil(t, image.SetOS("windows"))
+					h.AssertNil(t, image.SetOSVersion("10.0.17763.1234"))
+					h.AssertNil(t, newBase.SetOS("windows"))
+				})
+
+				it("accepts a new base with another revision of the same build", func() {
+					h.AssertNil(t, newBase.SetOSVersion("10.0.17763.5678"))
+
+					_, err := rebaseWithReport(imgutil.WithPlatformValidation())
+					h.AssertNil(t, err)
+					h.AssertEq(t, rebased, true)
+				})
+
+				it("fails when the new base is for another build", func() {
+					h.AssertNil(t, newBase.SetOSVersion("10.0.20348.1234"))
+
+					_, err := rebaseWithReport(imgutil.WithPlatformValidation())
+					h.AssertError(t, err, `expected a version of build "10.0.17763"`)
+					h.AssertEq(t, errors.Is(err, imgutil.ErrIncompatibleBase), true)
+					h.AssertEq(t, rebased, false)
+				})
+			})
+		})
+
+		when("labels are validated", func() {
+			it.Before(func() {
+				h.AssertNil(t, image.SetLabel("io.buildpacks.stack.id", "some-stack"))
+			})
+
+			it("rebases the image when the new base has the same values", func() {
+				h.AssertNil(t, newBase.SetLabel("io.buildpacks.stack.id", "some-stack"))
+
+				_, err := rebaseWithReport(imgutil.WithLabelValidation("io.buildpacks.stack.id"))
+				h.AssertNil(t, err)
+				h.AssertEq(t, rebased, true)
+			})
+
+			it("fails when the new base has another value", func() {
+				h.AssertNil(t, newBase.SetLabel("io.buildpacks.stack.id", "other-stack"))
+
+				_, err := rebaseWithReport(imgutil.WithLabelValidation("io.buildpacks.stack.id"))
+				h.AssertError(t, err, `new base image "some-new-base" has label "io.buildpacks.stack.id" set to "other-stack", expected "some-stack"`)
+				h.AssertEq(t, errors.Is(err, imgutil.ErrIncompatibleBase), true)
+				h.AssertEq(t, rebased, false)
+			})
+
+			it("fails when the new base does not have the label", func() {
+				_, err := rebaseWithReport(imgutil.WithLabelValidation("io.buildpacks.stack.id"))
+				h.AssertEq(t, errors.Is(err, imgutil.ErrIncompatibleBase), true)
+				h.AssertEq(t, rebased, false)
+			})
+		})
+	})
+}
@@ -0,0 +1,199 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/buildpacks/imgutil"
+)
+
+// CNBImage wraps an imgutil.CNBImageCore and implements the methods needed to complete the imgutil.Image interface
+// for images in a registry. Unlike Image, it shares the getters, setters and modifiers of the other backends.
+type CNBImage struct {
+	*imgutil.CNBImageCore
+	store               *Store
+	addEmptyLayerOnSave bool
+	baseImageSource     string
+	prevImageSource     string
+}
+
+var _ imgutil.Image = &CNBImage{}
+var _ imgutil.RebaseReporter = &CNBImage{}
+
+// NewCNBImage returns a new image built on imgutil.CNBImageCore that can be modified and saved to a registry.
+// It accepts the same options as NewImage.
+func NewCNBImage(repoName string, keychain authn.Keychain, ops ...ImageOption) (*CNBImage, error) {
+	imageOpts := &options{}
+	for _, op := range ops {
+		if err := op(imageOpts); err != nil {
+			return nil, err
+		}
+	}
+
+	platform := defaultPlatform()
+	if (imageOpts.platform != imgutil.Platform{}) {
+		platform = imageOpts.platform
+	}
+
+	var (
+		store   = newStore(keychain, imageOpts)
+		image   = &CNBImage{store: store, addEmptyLayerOnSave: imageOpts.addEmptyLayerOnSave}
+		err     error
+		options = imgutil.ImageOptions{
+			Config:          imageOpts.config,
+			CreatedAt:       imageOpts.createdAt,
+			Platform:        platform,
+			PreserveHistory: imageOpts.withHistory,
+			MediaTypes:      imageOpts.mediaTypes,
+			Progress:        imageOpts.progress,
+		}
+	)
+	if imageOpts.prevImageRepoName != "" {
+		options.PreviousImage, image.prevImageSource, err = store.readImage(imageOpts.prevImageRepoName, platform, imageOpts.mirrors, imageOpts.strict)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if imageOpts.baseImageRepoName != "" {
+		options.BaseImage, image.baseImageSource, err = store.readImage(imageOpts.baseImageRepoName, platform, imageOpts.mirrors, imageOpts.strict)
+		if err != nil {
+			return nil, err
+		}
+		if imageOpts.withHistory {
+			if options.BaseImage, err = imgutil.OverrideHistoryIfNeeded(options.BaseImage); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if image.CNBImageCore, err = imgutil.NewCNBImage(repoName, store, options); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+func (i *CNBImage) Found() bool {
+	return i.FoundContext(context.Background())
+}
+
+func (i *CNBImage) FoundContext(ctx context.Context) bool {
+	return imgutil.ContextStore(i.Store).ContainsContext(ctx, i.Name())
+}
+
+func (i *CNBImage) Identifier() (imgutil.Identifier, error) {
+	return i.store.image(i.Name(), i.Image).Identifier()
+}
+
+// GetLayer returns a reader of the uncompressed contents of the layer with the given diff ID,
+// fetching it from the registry if it is a layer of the base or previous image.
+func (i *CNBImage) GetLayer(diffID string) (io.ReadCloser, error) {
+	return i.GetLayerContext(context.Background(), diffID)
+}
+
+// GetLayerContext is like GetLayer, but aborts fetching the layer from the registry when ctx is done.
+func (i *CNBImage) GetLayerContext(ctx context.Context, diffID string) (io.ReadCloser, error) {
+	return i.store.image(i.Name(), i.Image).GetLayerContext(ctx, diffID)
+}
+
+// BaseImageFound tells whether the image provided with FromBaseImage was found.
+// It is false if no base image was provided, or if it could not be resolved and the image was created from scratch.
+func (i *CNBImage) BaseImageFound() bool {
+	return i.baseImageSource != ""
+}
+
+// BaseImageSource returns the name the base image was read by: the name provided with FromBaseImage,
+// or its name in the mirror that served it. It is empty if the base image was not found.
+func (i *CNBImage) BaseImageSource() string {
+	return i.baseImageSource
+}
+
+// PreviousImageSource returns the name the previous image was read by: the name provided with WithPreviousImage,
+// or its name in the mirror that served it. It is empty if the previous image was not found.
+func (i *CNBImage) PreviousImageSource() string {
+	return i.prevImageSource
+}
+
+// modifiers
+
+func (i *CNBImage) Delete() error {
+	return i.DeleteContext(context.Background())
+}
+
+func (i *CNBImage) DeleteContext(ctx context.Context) error {
+	id, err := i.Identifier()
+	if err != nil {
+		return err
+	}
+	return imgutil.ContextStore(i.Store).DeleteContext(ctx, id.String())
+}
+
+// RebaseWithReport is like Rebase, but first validates the new base as requested and reports what the rebase changed.
+func (i *CNBImage) RebaseWithReport(baseTopLayerDiffID string, withNewBase imgutil.Image, ops ...imgutil.RebaseOption) (imgutil.RebaseReport, error) {
+	return imgutil.RebaseWithReport(i, baseTopLayerDiffID, withNewBase, func() error {
+		return i.Rebase(baseTopLayerDiffID, withNewBase)
+	}, ops...)
+}
+
+func (i *CNBImage) Save(additionalNames ...string) error {
+	return i.SaveAsContext(context.Background(), i.Name(), additionalNames...)
+}
+
+func (i *CNBImage) SaveContext(ctx context.Context, additionalNames ...string) error {
+	return i.SaveAsContext(ctx, i.Name(), additionalNames...)
+}
+
+func (i *CNBImage) SaveAs(name string, additionalNames ...string) error {
+	return i.SaveAsContext(context.Background(), name, additionalNames...)
+}
+
+// SaveAsContext is like SaveAs, but aborts any in-flight push when ctx is done.
+func (i *CNBImage) SaveAsContext(ctx context.Context, name string, additionalNames ...string) error {
+	_, err := i.saveAs(ctx, name, additionalNames...)
+	return err
+}
+
+func (i *CNBImage) SaveWithResult(additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return i.saveAs(context.Background(), i.Name(), additionalNames...)
+}
+
+func (i *CNBImage) SaveAsWithResult(name string, additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return i.saveAs(context.Background(), name, additionalNames...)
+}
+
+func (i *CNBImage) saveAs(ctx context.Context, name string, additionalNames ...string) ([]imgutil.SaveResult, error) {
+	if err := i.prepareForSave(); err != nil {
+		return nil, err
+	}
+	return imgutil.ContextStore(i.Store).SaveContext(ctx, i, name, additionalNames...)
+}
+
+// SaveFile saves the image as a docker archive tagged with the image name, and provides its filesystem location.
+func (i *CNBImage) SaveFile() (string, error) {
+	if err := i.prepareForSave(); err != nil {
+		return "", err
+	}
+	return i.Store.SaveFile(i, i.Name())
+}
+
+// helpers
+
+// prepareForSave adds an empty layer to an image without layers, when requested with AddEmptyLayerOnSave.
+func (i *CNBImage) prepareForSave() error {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return fmt.Errorf("getting layers: %w", err)
+	}
+	if len(layers) > 0 || !i.addEmptyLayerOnSave {
+		return nil
+	}
+	if i.Image, err = mutate.AppendLayers(i.Image, static.NewLayer([]byte{}, types.OCILayer)); err != nil {
+		return fmt.Errorf("adding empty layer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package remote_test
+
+import (
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/imgutil/remote"
+	h "github.com/buildpacks/imgutil/testhelpers"
+)
+
+func TestCNBImage(t *testing.T) {
+	spec.Run(t, "CNBImage", testCNBImage, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testCNBImage(t *testing.T, when spec.G, it spec.S) {
+	var (
+		server *httptest.Server
+		host   string
+		opts   []remote.ImageOption
+	)
+
+	it.Before(func() {
+		server = httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+		host = strings.TrimPrefix(server.URL, "http://")
+		opts = []remote.ImageOption{remote.WithRegistryConfig(host, remote.RegistryConfig{PlainHTTP: true})}
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	when("#Save", func() {
+		it("pushes the image with each name, and reads it back as a base image", func() {
+			image, err := remote.NewCNBImage(host+"/some-image", authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			h.AssertEq(t, image.Kind(), "remote")
+			h.AssertEq(t, image.Found(), false)
+
+			layerPath, err := h.CreateSingleFileLayerTar("/some-file.txt", "some-content", "linux")
+			h.AssertNil(t, err)
+			defer os.Remove(layerPath)
+			h.AssertNil(t, image.AddLayer(layerPath))
+			h.AssertNil(t, image.SetLabel("some-label", "some-value"))
+			diffID, err := image.TopLayer()
+			h.AssertNil(t, err)
+
+			results, err := image.SaveWithResult(host + "/other-image:some-tag")
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(results), 2)
+			h.AssertEq(t, results[0].Digest, results[1].Digest)
+			h.AssertEq(t, image.Found(), true)
+			identifier, err := image.Identifier()
+			h.AssertNil(t, err)
+			h.AssertEq(t, identifier.String(), host+"/some-image@"+results[0].Digest.String())
+
+			rebuilt, err := remote.NewCNBImage(host+"/new-image", authn.DefaultKeychain, append(opts,
+				remote.FromBaseImage(host+"/other-image:some-tag"),
+			)...)
+			h.AssertNil(t, err)
+			h.AssertEq(t, rebuilt.BaseImageFound(), true)
+			label, err := rebuilt.Label("some-label")
+			h.AssertNil(t, err)
+			h.AssertEq(t, label, "some-value")
+			rc, err := rebuilt.GetLayer(diffID)
+			h.AssertNil(t, err)
+			defer rc.Close()
+		})
+
+		it("adds an empty layer to an image without layers when requested", func() {
+			image, err := remote.NewCNBImage(host+"/some-image", authn.DefaultKeychain, append(opts, remote.AddEmptyLayerOnSave())...)
+			h.AssertNil(t, err)
+			h.AssertNil(t, image.Save())
+
+			layers, err := image.UnderlyingImage().Layers()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(layers), 1)
+		})
+
+		it("reuses layers of the previous image", func() {
+			prevImage, err := remote.NewCNBImage(host+"/some-image", authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			layerPath, err := h.CreateSingleFileLayerTar("/some-file.txt", "some-content", "linux")
+			h.AssertNil(t, err)
+			defer os.Remove(layerPath)
+			h.AssertNil(t, prevImage.AddLayer(layerPath))
+			diffID, err := prevImage.TopLayer()
+			h.AssertNil(t, err)
+			h.AssertNil(t, prevImage.Save())
+
+			image, err := remote.NewCNBImage(host+"/some-image", authn.DefaultKeychain, append(opts,
+				remote.WithPreviousImage(host+"/some-image"),
+			)...)
+			h.AssertNil(t, err)
+			h.AssertEq(t, image.PreviousImageSource(), host+"/some-image")
+			h.AssertNil(t, image.ReuseLayer(diffID))
+			h.AssertNil(t, image.Save())
+
+			topLayer, err := image.TopLayer()
+			h.AssertNil(t, err)
+			h.AssertEq(t, topLayer, diffID)
+		})
+	})
+
+	when("#Delete", func() {
+		it("deletes the image from the registry", func() {
+			image, err := remote.NewCNBImage(host+"/some-image", authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			h.AssertNil(t, image.Save())
+			identifier, err := image.Identifier()
+			h.AssertNil(t, err)
+			byDigest, err := remote.NewCNBImage(identifier.String(), authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			h.AssertEq(t, byDigest.Found(), true)
+
+			h.AssertNil(t, image.Delete())
+			h.AssertEq(t, byDigest.Found(), false)
+		})
+	})
+}
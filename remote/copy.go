@@ -0,0 +1,28 @@
+package remote
+
+import (
+	"context"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/buildpacks/imgutil"
+)
+
+var _ imgutil.CopyTarget = &Image{}
+
+// SaveCopy pushes image as is to the repository of the image, and makes it the content of the image.
+// Layers of images read from the same registry are mounted from their repositories.
+func (i *Image) SaveCopy(ctx context.Context, image v1.Image) (imgutil.SaveResult, error) {
+	if err := ctx.Err(); err != nil {
+		return imgutil.SaveResult{}, err
+	}
+	result, err := imgutil.NewSaveResult(i.repoName, image)
+	if err != nil {
+		return imgutil.SaveResult{}, err
+	}
+	i.image = image
+	if _, err := i.doSave(ctx, i.repoName, &result); err != nil {
+		return imgutil.SaveResult{}, err
+	}
+	return result, nil
+}
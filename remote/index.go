@@ -0,0 +1,112 @@
+package remote
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/imgutil"
+)
+
+// ImageIndex wraps an imgutil.CNBIndex and implements the methods needed to save it to a registry.
+type ImageIndex struct {
+	*imgutil.CNBIndex
+	keychain         authn.Keychain
+	registrySettings map[string]RegistryConfig
+}
+
+var _ imgutil.ImageIndex = &ImageIndex{}
+
+// NewIndex returns a new ImageIndex that can be modified and saved to a registry.
+func NewIndex(repoName string, keychain authn.Keychain, ops ...IndexOption) (*ImageIndex, error) {
+	indexOpts := &indexOptions{}
+	for _, op := range ops {
+		if err := op(indexOpts); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		baseIndex v1.ImageIndex
+		err       error
+	)
+	if indexOpts.baseIndexRepoName != "" {
+		reg := getRegistry(indexOpts.baseIndexRepoName, indexOpts.registrySettings)
+		baseIndex, err = newV1Index(keychain, indexOpts.baseIndexRepoName, reg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cnbIndex, err := imgutil.NewCNBIndex(repoName, imgutil.IndexOptions{
+		BaseIndex:  baseIndex,
+		MediaTypes: indexOpts.mediaTypes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ImageIndex{
+		CNBIndex:         cnbIndex,
+		keychain:         keychain,
+		registrySettings: indexOpts.registrySettings,
+	}, nil
+}
+
+// newV1Index returns the index with the given name, or nil if the index does not exist.
+func newV1Index(keychain authn.Keychain, repoName string, reg RegistryConfig) (v1.ImageIndex, error) {
+	ref, auth, err := referenceForRepoName(keychain, repoName, reg)
+	if err != nil {
+		return nil, err
+	}
+	rt, err := reg.transport()
+	if err != nil {
+		return nil, err
+	}
+	desc, err := remote.Get(ref, remote.WithAuth(auth), remote.WithTransport(rt))
+	if err != nil {
+		if transportErr, ok := err.(*transport.Error); ok && transportErr.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, registryError(errors.Wrapf(err, "connect to repo store %q", repoName))
+	}
+	if !desc.MediaType.IsIndex() {
+		return nil, fmt.Errorf("expected %q to be an image index; got media type %q", repoName, desc.MediaType)
+	}
+	return desc.ImageIndex()
+}
+
+func (i *ImageIndex) Save(additionalNames ...string) error {
+	var diagnostics []imgutil.SaveDiagnostic
+	for _, n := range append([]string{i.Name()}, additionalNames...) {
+		if err := i.doSave(n); err != nil {
+			diagnostics = append(diagnostics, imgutil.SaveDiagnostic{ImageName: n, Cause: err})
+		}
+	}
+	if len(diagnostics) > 0 {
+		return imgutil.SaveError{Errors: diagnostics}
+	}
+	return nil
+}
+
+func (i *ImageIndex) doSave(indexName string) error {
+	reg := getRegistry(indexName, i.registrySettings)
+	ref, auth, err := referenceForRepoName(i.keychain, indexName, reg)
+	if err != nil {
+		return err
+	}
+	transport, err := reg.transport()
+	if err != nil {
+		return err
+	}
+
+	return registryError(remote.WriteIndex(ref, i.UnderlyingIndex(),
+		remote.WithAuth(auth),
+		remote.WithTransport(transport),
+	))
+}
@@ -0,0 +1,116 @@
+package remote_test
+
+import (
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sclevine/spec"
+
+	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/remote"
+	h "github.com/buildpacks/imgutil/testhelpers"
+)
+
+func testIndex(t *testing.T, when spec.G, it spec.S) {
+	var (
+		indexName string
+		amd64Img  *remote.Image
+		arm64Img  *remote.Image
+	)
+
+	newPlatformImage := func(platform imgutil.Platform) *remote.Image {
+		img, err := remote.NewImage(newTestImageName(), authn.DefaultKeychain, remote.WithDefaultPlatform(platform))
+		h.AssertNil(t, err)
+		h.AssertNil(t, img.Save())
+		return img
+	}
+
+	digestOf := func(img *remote.Image) v1.Hash {
+		digest, err := img.UnderlyingImage().Digest()
+		h.AssertNil(t, err)
+		return digest
+	}
+
+	it.Before(func() {
+		indexName = newTestImageName("index-test")
+		amd64Img = newPlatformImage(imgutil.Platform{OS: "linux", Architecture: "amd64"})
+		arm64Img = newPlatformImage(imgutil.Platform{OS: "linux", Architecture: "arm64"})
+	})
+
+	when("#NewIndex", func() {
+		it("defaults to an empty OCI image index", func() {
+			idx, err := remote.NewIndex(indexName, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+
+			manifests, err := idx.Manifests()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(manifests), 0)
+
+			mediaType, err := idx.UnderlyingIndex().MediaType()
+			h.AssertNil(t, err)
+			h.AssertEq(t, mediaType, types.OCIImageIndex)
+		})
+
+		when("#FromBaseIndex", func() {
+			it("loads the manifests of the existing index", func() {
+				base, err := remote.NewIndex(newTestImageName("base-index"), authn.DefaultKeychain)
+				h.AssertNil(t, err)
+				h.AssertNil(t, base.Add(amd64Img))
+				h.AssertNil(t, base.Save())
+
+				idx, err := remote.NewIndex(indexName, authn.DefaultKeychain, remote.FromBaseIndex(base.Name()))
+				h.AssertNil(t, err)
+
+				manifests, err := idx.Manifests()
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(manifests), 1)
+				h.AssertEq(t, manifests[0].Digest, digestOf(amd64Img))
+			})
+
+			it("ignores a missing index", func() {
+				idx, err := remote.NewIndex(indexName, authn.DefaultKeychain, remote.FromBaseIndex(newTestImageName("missing-index")))
+				h.AssertNil(t, err)
+
+				manifests, err := idx.Manifests()
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(manifests), 0)
+			})
+		})
+	})
+
+	when("#Save", func() {
+		it("pushes the index with the platform of each image", func() {
+			idx, err := remote.NewIndex(indexName, authn.DefaultKeychain, remote.WithIndexMediaTypes(imgutil.DockerTypes))
+			h.AssertNil(t, err)
+			h.AssertNil(t, idx.Add(amd64Img))
+			h.AssertNil(t, idx.Add(arm64Img))
+
+			h.AssertNil(t, idx.Save())
+
+			saved, err := remote.NewIndex(indexName, authn.DefaultKeychain, remote.FromBaseIndex(indexName))
+			h.AssertNil(t, err)
+
+			mediaType, err := saved.UnderlyingIndex().MediaType()
+			h.AssertNil(t, err)
+			h.AssertEq(t, mediaType, types.DockerManifestList)
+
+			platform, err := saved.Platform(digestOf(arm64Img))
+			h.AssertNil(t, err)
+			h.AssertEq(t, platform.Architecture, "arm64")
+		})
+
+		it("reports failures for additional names", func() {
+			idx, err := remote.NewIndex(indexName, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+			h.AssertNil(t, idx.Add(amd64Img))
+
+			err = idx.Save("🧨")
+			saveErr, ok := err.(imgutil.SaveError)
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, len(saveErr.Errors), 1)
+			h.AssertEq(t, saveErr.Errors[0].ImageName, "🧨")
+		})
+	})
+}
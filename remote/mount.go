@@ -0,0 +1,243 @@
+package remote
+
+import (
+	"net/http"
+	"path"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/buildpacks/imgutil"
+)
+
+// recordMountSources records the repository named repoName as a source that the layers of image can be mounted from
+// when the image they end up in is saved to another repository of the same registry.
+// repoName is the name the image was requested by, so layers read from a mirror are mounted from the mirrored repository.
+func (i *Image) recordMountSources(image v1.Image, repoName string) error {
+	ref, err := name.ParseReference(repoName, getRegistry(repoName, i.registrySettings).nameOptions()...)
+	if err != nil {
+		return err
+	}
+	layers, err := image.Layers()
+	if err != nil {
+		return err
+	}
+	if i.mountSources == nil {
+		i.mountSources = make(map[v1.Hash]name.Repository)
+	}
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return err
+		}
+		if _, ok := i.mountSources[digest]; !ok {
+			i.mountSources[digest] = ref.Context()
+		}
+	}
+	return nil
+}
+
+// mountableImage makes the layers of an image that are known to be in another repository of the target registry
+// mountable from that repository, so that the registry mounts them instead of them being pushed again.
+type mountableImage struct {
+	v1.Image
+	target  name.Repository
+	sources map[v1.Hash]name.Repository
+}
+
+func newMountableImage(image v1.Image, target name.Repository, sources map[v1.Hash]name.Repository) v1.Image {
+	if len(sources) == 0 {
+		return image
+	}
+	return &mountableImage{Image: image, target: target, sources: sources}
+}
+
+func (i *mountableImage) Layers() ([]v1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	mountable := make([]v1.Layer, len(layers))
+	for idx, layer := range layers {
+		if mountable[idx], err = i.mountable(layer); err != nil {
+			return nil, err
+		}
+	}
+	return mountable, nil
+}
+
+func (i *mountableImage) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	layer, err := i.Image.LayerByDigest(h)
+	if err != nil {
+		return nil, err
+	}
+	return i.mountable(layer)
+}
+
+// ConfigLayer makes the config blob mountable as well, when its source is known.
+func (i *mountableImage) ConfigLayer() (v1.Layer, error) {
+	layer, err := partial.ConfigLayer(i.Image)
+	if err != nil {
+		return nil, err
+	}
+	return i.mountable(layer)
+}
+
+func (i *mountableImage) mountable(layer v1.Layer) (v1.Layer, error) {
+	digest, err := layer.Digest()
+	if err != nil {
+		return nil, err
+	}
+	source, ok := i.sources[digest]
+	if !ok || source.RegistryStr() != i.target.RegistryStr() {
+		return layer, nil
+	}
+	if ml, ok := layer.(*remote.MountableLayer); ok {
+		if ml.Reference.Context().RegistryStr() == i.target.RegistryStr() {
+			// already mountable from the target registry
+			return layer, nil
+		}
+		// read from a mirror, but mountable from the mirrored repository
+		layer = ml.Layer
+	}
+	return &remote.MountableLayer{Layer: layer, Reference: source.Digest(digest.String())}, nil
+}
+
+// blobSources returns repo as the source of every layer and of the config blob of image.
+func blobSources(image v1.Image, repo name.Repository) (map[v1.Hash]name.Repository, error) {
+	layers, err := image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	config, err := image.ConfigName()
+	if err != nil {
+		return nil, err
+	}
+	sources := map[v1.Hash]name.Repository{config: repo}
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return nil, err
+		}
+		sources[digest] = repo
+	}
+	return sources, nil
+}
+
+// layerRecorder records what happened to each blob during a push:
+// whether the registry already had it, mounted it from another repository, or it was uploaded.
+// Each layer that is not uploaded is reported to the progress handler, if any, once this is known; the bytes of
+// uploaded layers are reported as they are read (see imgutil.ProgressReportingImage).
+type layerRecorder struct {
+	inner   http.RoundTripper
+	sizes   map[string]int64 // layer digest -> layer size
+	handler imgutil.ProgressHandler
+
+	mu     sync.Mutex
+	phases map[string]imgutil.ProgressPhase // blob digest -> phase
+}
+
+func newLayerRecorder(inner http.RoundTripper, image v1.Image, handler imgutil.ProgressHandler) (*layerRecorder, error) {
+	layers, err := image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64, len(layers))
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return nil, err
+		}
+		size, err := layer.Size()
+		if err != nil {
+			return nil, err
+		}
+		sizes[digest.String()] = size
+	}
+	return &layerRecorder{
+		inner:   inner,
+		sizes:   sizes,
+		handler: handler,
+		phases:  make(map[string]imgutil.ProgressPhase),
+	}, nil
+}
+
+func (r *layerRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.inner.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	switch {
+	case req.Method == http.MethodHead && resp.StatusCode == http.StatusOK:
+		// blob existence check: HEAD /v2/<name>/blobs/<digest>
+		if path.Base(path.Dir(req.URL.Path)) == "blobs" {
+			r.record(path.Base(req.URL.Path), imgutil.PhaseExists)
+		}
+	case req.Method == http.MethodPost && resp.StatusCode == http.StatusCreated:
+		// cross-repository blob mount: POST /v2/<name>/blobs/uploads/?mount=<digest>&from=<repository name>
+		if mount := req.URL.Query().Get("mount"); mount != "" {
+			r.record(mount, imgutil.PhaseMounted)
+		}
+	case req.Method == http.MethodPut && resp.StatusCode == http.StatusCreated:
+		// completed blob upload: PUT <location>?digest=<digest>
+		if digest := req.URL.Query().Get("digest"); digest != "" {
+			r.record(digest, imgutil.PhaseUploading)
+		}
+	}
+	return resp, nil
+}
+
+// record keeps the most significant phase seen for the blob,
+// so that a blob uploaded by a failed attempt is still reported as uploaded when a retry finds it.
+func (r *layerRecorder) record(digest string, phase imgutil.ProgressPhase) {
+	rank := map[imgutil.ProgressPhase]int{imgutil.PhaseExists: 1, imgutil.PhaseMounted: 2, imgutil.PhaseUploading: 3}
+	r.mu.Lock()
+	if rank[phase] <= rank[r.phases[digest]] {
+		r.mu.Unlock()
+		return
+	}
+	r.phases[digest] = phase
+	r.mu.Unlock()
+
+	size, ok := r.sizes[digest]
+	if !ok || r.handler == nil || phase == imgutil.PhaseUploading {
+		// not a layer, e.g. the config blob, or already reported as it was read
+		return
+	}
+	hash, err := v1.NewHash(digest)
+	if err != nil {
+		return
+	}
+	r.handler(imgutil.Progress{Digest: hash, Complete: size, Total: size, Phase: phase})
+}
+
+// report sets the layers of image that were mounted and uploaded on result, in the order of the image manifest.
+func (r *layerRecorder) report(image v1.Image, result *imgutil.SaveResult) error {
+	layers, err := image.Layers()
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[v1.Hash]bool, len(layers))
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return err
+		}
+		if seen[digest] {
+			continue
+		}
+		seen[digest] = true
+		switch r.phases[digest.String()] {
+		case imgutil.PhaseMounted:
+			result.MountedLayers = append(result.MountedLayers, digest)
+		case imgutil.PhaseUploading:
+			result.UploadedLayers = append(result.UploadedLayers, digest)
+		}
+	}
+	return nil
+}
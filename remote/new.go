@@ -1,11 +1,10 @@
 package remote
 
 import (
-	"io"
+	"context"
 	"net/http"
 	"runtime"
 	"strings"
-	"time"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
@@ -46,17 +45,22 @@ func NewImage(repoName string, keychain authn.Keychain, ops ...ImageOption) (*Im
 		image:               image,
 		addEmptyLayerOnSave: imageOpts.addEmptyLayerOnSave,
 		withHistory:         imageOpts.withHistory,
+		progress:            imageOpts.progress,
 		registrySettings:    imageOpts.registrySettings,
+		blobCache:           imageOpts.blobCache,
+	}
+	if imageOpts.retryPolicy != nil {
+		ri.retryPolicy = *imageOpts.retryPolicy
 	}
 
 	if imageOpts.prevImageRepoName != "" {
-		if err := processPreviousImageOption(ri, imageOpts.prevImageRepoName, platform); err != nil {
+		if err := processPreviousImageOption(ri, imageOpts.prevImageRepoName, platform, imageOpts.mirrors, imageOpts.strict); err != nil {
 			return nil, err
 		}
 	}
 
 	if imageOpts.baseImageRepoName != "" {
-		if err := processBaseImageOption(ri, imageOpts.baseImageRepoName, platform); err != nil {
+		if err := processBaseImageOption(ri, imageOpts.baseImageRepoName, platform, imageOpts.mirrors, imageOpts.strict); err != nil {
 			return nil, err
 		}
 	}
@@ -104,6 +108,8 @@ func emptyImage(platform imgutil.Platform) (v1.Image, error) {
 		History:      []v1.History{},
 		OS:           platform.OS,
 		OSVersion:    platform.OSVersion,
+		Variant:      platform.Variant,
+		OSFeatures:   platform.OSFeatures(),
 		RootFS: v1.RootFS{
 			Type:    "layers",
 			DiffIDs: []v1.Hash{},
@@ -143,13 +149,24 @@ func prepareNewWindowsImage(ri *Image) error {
 	return nil
 }
 
-func processPreviousImageOption(ri *Image, prevImageRepoName string, platform imgutil.Platform) error {
-	reg := getRegistry(prevImageRepoName, ri.registrySettings)
+func processPreviousImageOption(ri *Image, prevImageRepoName string, platform imgutil.Platform, mirrors map[string][]string, strict bool) error {
+	sources := readSources(prevImageRepoName, mirrors, func(repoName string) RegistryConfig {
+		return getRegistry(repoName, ri.registrySettings)
+	})
 
-	prevImage, err := NewV1Image(prevImageRepoName, ri.keychain, WithV1DefaultPlatform(platform), WithV1RegistrySetting(reg.insecure))
+	prevImage, source, err := newV1Image(context.Background(), ri.keychain, sources, platform, ri.retryPolicy, strict)
 	if err != nil {
 		return err
 	}
+	ri.prevImageSource = source
+	if ri.blobCache != nil {
+		prevImage = ri.blobCache.Image(prevImage)
+	}
+	if source != "" {
+		if err := ri.recordMountSources(prevImage, prevImageRepoName); err != nil {
+			return errors.Wrapf(err, "getting layers for previous image with repo name %q", prevImageRepoName)
+		}
+	}
 
 	prevLayers, err := prevImage.Layers()
 	if err != nil {
@@ -171,15 +188,6 @@ func processPreviousImageOption(ri *Image, prevImageRepoName string, platform im
 	return nil
 }
 
-func getRegistry(repoName string, registrySettings map[string]registrySetting) registrySetting {
-	for prefix, r := range registrySettings {
-		if strings.HasPrefix(repoName, prefix) {
-			return r
-		}
-	}
-	return registrySetting{}
-}
-
 // NewV1Image returns a new v1.Image
 func NewV1Image(baseImageRepoName string, keychain authn.Keychain, ops ...V1ImageOption) (v1.Image, error) {
 	imageOpts := &v1Options{}
@@ -194,20 +202,54 @@ func NewV1Image(baseImageRepoName string, keychain authn.Keychain, ops ...V1Imag
 		platform = imageOpts.platform
 	}
 
-	reg := registrySetting{}
-	if (imageOpts.registrySetting != registrySetting{}) {
-		reg = imageOpts.registrySetting
+	sources := readSources(baseImageRepoName, imageOpts.mirrors, func(string) RegistryConfig {
+		return imageOpts.registrySetting
+	})
+	var policy RetryPolicy
+	if imageOpts.retryPolicy != nil {
+		policy = *imageOpts.retryPolicy
 	}
 
-	baseImage, err := newV1Image(keychain, baseImageRepoName, platform, reg)
+	baseImage, _, err := newV1Image(context.Background(), keychain, sources, platform, policy, imageOpts.strict)
 	if err != nil {
 		return nil, err
 	}
+	if imageOpts.blobCache != nil {
+		baseImage = imageOpts.blobCache.Image(baseImage)
+	}
 	return baseImage, nil
 }
 
-func newV1Image(keychain authn.Keychain, repoName string, platform imgutil.Platform, reg registrySetting) (v1.Image, error) {
-	ref, auth, err := referenceForRepoName(keychain, repoName, reg.insecure)
+// newV1Image returns the image read from the first of the provided sources that serves it, and the name it was read by.
+// The last source is the registry named in the image reference, which is only tried after any mirror of it.
+// Unless strict is set, an empty image, and an empty name, are returned when the registry doesn't serve the image,
+// i.e. when the image doesn't exist, is not accessible, or has no image manifest for the requested platform.
+func newV1Image(ctx context.Context, keychain authn.Keychain, sources []imageSource, platform imgutil.Platform, policy RetryPolicy, strict bool) (v1.Image, string, error) {
+	mirrors, upstream := sources[:len(sources)-1], sources[len(sources)-1]
+	for _, mirror := range mirrors {
+		if image, err := readV1Image(ctx, keychain, mirror, platform, policy); err == nil {
+			return image, mirror.repoName, nil
+		}
+		// fall back to the next mirror, or to the registry itself
+	}
+
+	image, err := readV1Image(ctx, keychain, upstream, platform, policy)
+	if err != nil {
+		if isMissingImage(err) && !strict {
+			image, err = emptyImage(platform)
+			return image, "", err
+		}
+		return nil, "", imageError(err, upstream.repoName)
+	}
+	return image, upstream.repoName, nil
+}
+
+func readV1Image(ctx context.Context, keychain authn.Keychain, source imageSource, platform imgutil.Platform, policy RetryPolicy) (v1.Image, error) {
+	ref, auth, err := referenceForRepoName(keychain, source.repoName, source.reg)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := source.reg.transport()
 	if err != nil {
 		return nil, err
 	}
@@ -216,44 +258,51 @@ func newV1Image(keychain authn.Keychain, repoName string, platform imgutil.Platf
 		Architecture: platform.Architecture,
 		OS:           platform.OS,
 		OSVersion:    platform.OSVersion,
+		Variant:      platform.Variant,
+		OSFeatures:   platform.OSFeatures(),
 	}
 
 	var image v1.Image
-	for i := 0; i <= maxRetries; i++ {
-		time.Sleep(100 * time.Duration(i) * time.Millisecond) // wait if retrying
-		image, err = remote.Image(ref,
+	err = policy.do(ctx, transport, func(rt http.RoundTripper) error {
+		image, err = remote.Image(ref, append(policy.remoteOptions(),
 			remote.WithAuth(auth),
 			remote.WithPlatform(v1Platform),
-			remote.WithTransport(getTransport(reg.insecure)),
-		)
-		if err != nil {
-			if err == io.EOF && i != maxRetries {
-				continue // retry if EOF
-			}
-			if transportErr, ok := err.(*transport.Error); ok && len(transportErr.Errors) > 0 {
-				switch transportErr.StatusCode {
-				case http.StatusNotFound, http.StatusUnauthorized:
-					return emptyImage(platform)
-				}
-			}
-			if strings.Contains(err.Error(), "no child with platform") {
-				return emptyImage(platform)
-			}
-			return nil, errors.Wrapf(err, "connect to repo store %q", repoName)
-		}
-		break
+			remote.WithTransport(rt),
+			remote.WithContext(ctx),
+		)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return image, nil
 }
 
-func referenceForRepoName(keychain authn.Keychain, ref string, insecure bool) (name.Reference, authn.Authenticator, error) {
-	var auth authn.Authenticator
-	opts := []name.Option{name.WeakValidation}
-	if insecure {
-		opts = append(opts, name.Insecure)
+// isMissingImage tells whether err means that the image doesn't exist, is not accessible,
+// or has no image manifest for the requested platform.
+func isMissingImage(err error) bool {
+	if transportErr, ok := err.(*transport.Error); ok && len(transportErr.Errors) > 0 {
+		switch transportErr.StatusCode {
+		case http.StatusNotFound, http.StatusUnauthorized:
+			return true
+		}
 	}
-	r, err := name.ParseReference(ref, opts...)
+	return strings.Contains(err.Error(), "no child with platform")
+}
+
+// imageError returns err, from reading the image with the given name, wrapped with the matching imgutil error.
+func imageError(err error, repoName string) error {
+	wrapped := errors.Wrapf(err, "connect to repo store %q", repoName)
+	if strings.Contains(err.Error(), "no child with platform") {
+		return imgutil.WrapError(imgutil.ErrPlatformMismatch, wrapped)
+	}
+	return registryError(wrapped)
+}
+
+func referenceForRepoName(keychain authn.Keychain, ref string, reg RegistryConfig) (name.Reference, authn.Authenticator, error) {
+	var auth authn.Authenticator
+	r, err := name.ParseReference(ref, reg.nameOptions()...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -265,13 +314,23 @@ func referenceForRepoName(keychain authn.Keychain, ref string, insecure bool) (n
 	return r, auth, nil
 }
 
-func processBaseImageOption(ri *Image, baseImageRepoName string, platform imgutil.Platform) error {
-	reg := getRegistry(baseImageRepoName, ri.registrySettings)
+func processBaseImageOption(ri *Image, baseImageRepoName string, platform imgutil.Platform, mirrors map[string][]string, strict bool) error {
+	sources := readSources(baseImageRepoName, mirrors, func(repoName string) RegistryConfig {
+		return getRegistry(repoName, ri.registrySettings)
+	})
 	var err error
-	ri.image, err = NewV1Image(baseImageRepoName, ri.keychain, WithV1DefaultPlatform(platform), WithV1RegistrySetting(reg.insecure))
+	ri.image, ri.baseImageSource, err = newV1Image(context.Background(), ri.keychain, sources, platform, ri.retryPolicy, strict)
 	if err != nil {
 		return err
 	}
+	if ri.blobCache != nil {
+		ri.image = ri.blobCache.Image(ri.image)
+	}
+	if ri.baseImageSource != "" {
+		if err = ri.recordMountSources(ri.image, baseImageRepoName); err != nil {
+			return errors.Wrapf(err, "getting layers for base image with repo name %q", baseImageRepoName)
+		}
+	}
 	if !ri.withHistory {
 		return nil
 	}
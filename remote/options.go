@@ -1,11 +1,13 @@
 package remote
 
 import (
+	"fmt"
 	"time"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 
 	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/blobcache"
 )
 
 type ImageOption func(*options) error
@@ -17,9 +19,14 @@ type options struct {
 	createdAt           time.Time
 	addEmptyLayerOnSave bool
 	withHistory         bool
-	registrySettings    map[string]registrySetting
+	registrySettings    map[string]RegistryConfig
 	mediaTypes          imgutil.MediaTypes
 	config              *v1.Config
+	progress            imgutil.ProgressHandler
+	strict              bool
+	mirrors             map[string][]string
+	retryPolicy         *RetryPolicy
+	blobCache           *blobcache.Cache
 }
 
 // AddEmptyLayerOnSave (remote only) adds an empty layer before saving if the image has no layer at all.
@@ -41,6 +48,16 @@ func FromBaseImage(imageName string) ImageOption {
 	}
 }
 
+// WithBlobCache (remote only) reads the layers of the base image and the previous image through the given cache,
+// so that they are only downloaded from the registry when they are not in the cache.
+// This applies to GetLayer, Rebase, SaveFile and to copying the layers of the underlying image to other backends.
+func WithBlobCache(cache *blobcache.Cache) ImageOption {
+	return func(opts *options) error {
+		opts.blobCache = cache
+		return nil
+	}
+}
+
 // WithCreatedAt lets a caller set the created at timestamp for the image.
 // Defaults for a new image is imgutil.NormalizedDateTime
 func WithCreatedAt(createdAt time.Time) ImageOption {
@@ -57,7 +74,7 @@ func WithConfig(config *v1.Config) ImageOption {
 	}
 }
 
-// WithDefaultPlatform provides Architecture/OS/OSVersion defaults for the new image.
+// WithDefaultPlatform provides Architecture/OS/OSVersion/Variant/OSFeatures defaults for the new image.
 // Defaults for a new image are ignored when FromBaseImage returns an image.
 // FromBaseImage and WithPreviousImage will use the platform to choose an image from a manifest list.
 func WithDefaultPlatform(platform imgutil.Platform) ImageOption {
@@ -95,19 +112,82 @@ func WithPreviousImage(imageName string) ImageOption {
 	}
 }
 
+// WithProgress registers a handler that is called with the progress of each layer
+// while the image is pushed by Save or written by SaveFile.
+// When the image is pushed, the bytes of each layer are reported with its digest as the layer is uploaded, and a layer
+// that is not pushed because the registry already has it or mounted it from another repository is reported once
+// ("exists" or "mounted" phase). The bytes of all the blobs pushed so far are also reported without a layer digest.
+func WithProgress(handler imgutil.ProgressHandler) ImageOption {
+	return func(opts *options) error {
+		opts.progress = handler
+		return nil
+	}
+}
+
+// WithRegistryMirrors (remote only) registers mirrors to read the base image and the previous image from,
+// when they are in the given registry. The mirrors are tried in order, and the registry itself is only accessed
+// when none of them serves the image. The image itself is still saved to the registry named in its reference.
+// Each mirror is a registry, optionally followed by the path that the repositories of the registry are mirrored under,
+// e.g. "mirror.example.com/docker-hub"; it is accessed with the config registered for it with WithRegistryConfig.
+func WithRegistryMirrors(registry string, mirrors ...string) ImageOption {
+	return func(opts *options) error {
+		key, err := mirrorsKey(registry)
+		if err != nil {
+			return fmt.Errorf("invalid registry %q: %w", registry, err)
+		}
+		if len(opts.mirrors) == 0 {
+			opts.mirrors = make(map[string][]string)
+		}
+
+		opts.mirrors[key] = mirrors
+
+		return nil
+	}
+}
+
 // WithRegistrySetting (remote only) registers options to use when accessing images in a registry in order to construct
 // the image. The referenced images could include the base image, a previous image, or the image itself.
 // insecure parameter allows image references to be fetched without TLS.
 func WithRegistrySetting(repository string, insecure bool) ImageOption {
+	return WithRegistryConfig(repository, RegistryConfig{Insecure: insecure})
+}
+
+// WithRegistryConfig (remote only) is like WithRegistrySetting, but allows the TLS, proxy and transport
+// configuration to use when accessing images in the registry, or repository, to be provided.
+// When several repositories match an image reference, the config registered for the longest one is used.
+// The transport for the config is built once, so images created with the same option reuse connections to the registry.
+func WithRegistryConfig(repository string, config RegistryConfig) ImageOption {
+	config = config.withTransportCache()
 	return func(opts *options) error {
+		if err := config.validate(); err != nil {
+			return fmt.Errorf("invalid config for registry %q: %w", repository, err)
+		}
 		if len(opts.registrySettings) == 0 {
-			opts.registrySettings = make(map[string]registrySetting)
+			opts.registrySettings = make(map[string]RegistryConfig)
 		}
 
-		opts.registrySettings[repository] = registrySetting{
-			insecure: insecure,
-		}
+		opts.registrySettings[repository] = config
+
+		return nil
+	}
+}
+
+// WithRetryPolicy (remote only) sets the policy used to retry reading the base image and the previous image,
+// as well as Found, Valid, Save and Delete, when they fail with a transient error.
+// By default, the zero RetryPolicy is used.
+func WithRetryPolicy(policy RetryPolicy) ImageOption {
+	return func(opts *options) error {
+		opts.retryPolicy = &policy
+		return nil
+	}
+}
 
+// WithStrictBaseImage makes NewImage fail when the image provided with FromBaseImage or WithPreviousImage cannot be resolved,
+// instead of ignoring it. The returned error matches imgutil.ErrImageNotFound, imgutil.ErrUnauthorized
+// or imgutil.ErrPlatformMismatch, depending on why the image could not be resolved.
+func WithStrictBaseImage() ImageOption {
+	return func(opts *options) error {
+		opts.strict = true
 		return nil
 	}
 }
@@ -115,12 +195,25 @@ func WithRegistrySetting(repository string, insecure bool) ImageOption {
 // v1Options is used to configure the behavior when a v1.Image is created
 type v1Options struct {
 	platform        imgutil.Platform
-	registrySetting registrySetting
+	registrySetting RegistryConfig
+	strict          bool
+	mirrors         map[string][]string
+	retryPolicy     *RetryPolicy
+	blobCache       *blobcache.Cache
 }
 
 type V1ImageOption func(*v1Options) error
 
-// WithV1DefaultPlatform provides Architecture/OS/OSVersion defaults for the new v1.Image.
+// WithV1BlobCache reads the layers of the image through the given cache,
+// so that they are only downloaded from the registry when they are not in the cache.
+func WithV1BlobCache(cache *blobcache.Cache) V1ImageOption {
+	return func(opts *v1Options) error {
+		opts.blobCache = cache
+		return nil
+	}
+}
+
+// WithV1DefaultPlatform provides Architecture/OS/OSVersion/Variant/OSFeatures defaults for the new v1.Image.
 func WithV1DefaultPlatform(platform imgutil.Platform) V1ImageOption {
 	return func(opts *v1Options) error {
 		opts.platform = platform
@@ -130,10 +223,104 @@ func WithV1DefaultPlatform(platform imgutil.Platform) V1ImageOption {
 
 // WithV1RegistrySetting registers options to use when accessing images in a registry in order to construct a v1.Image.
 func WithV1RegistrySetting(insecure bool) V1ImageOption {
+	return WithV1RegistryConfig(RegistryConfig{Insecure: insecure})
+}
+
+// WithV1RegistryConfig is like WithV1RegistrySetting, but allows the TLS, proxy and transport configuration
+// to use when accessing the registry to be provided.
+func WithV1RegistryConfig(config RegistryConfig) V1ImageOption {
+	config = config.withTransportCache()
 	return func(opts *v1Options) error {
-		opts.registrySetting = registrySetting{
-			insecure: insecure,
+		if err := config.validate(); err != nil {
+			return fmt.Errorf("invalid registry config: %w", err)
 		}
+		opts.registrySetting = config
+		return nil
+	}
+}
+
+// WithV1RegistryMirrors registers mirrors to read the image from, when it is in the given registry.
+// The mirrors are tried in order, and the registry itself is only accessed when none of them serves the image.
+// The registry config provided with WithV1RegistryConfig is also used to access the mirrors.
+func WithV1RegistryMirrors(registry string, mirrors ...string) V1ImageOption {
+	return func(opts *v1Options) error {
+		key, err := mirrorsKey(registry)
+		if err != nil {
+			return fmt.Errorf("invalid registry %q: %w", registry, err)
+		}
+		if len(opts.mirrors) == 0 {
+			opts.mirrors = make(map[string][]string)
+		}
+		opts.mirrors[key] = mirrors
+		return nil
+	}
+}
+
+// WithV1RetryPolicy sets the policy used to retry reading the image when it fails with a transient error.
+// By default, the zero RetryPolicy is used.
+func WithV1RetryPolicy(policy RetryPolicy) V1ImageOption {
+	return func(opts *v1Options) error {
+		opts.retryPolicy = &policy
+		return nil
+	}
+}
+
+// WithV1Strict makes NewV1Image fail when the image cannot be resolved, instead of returning an empty image.
+// The returned error matches imgutil.ErrImageNotFound, imgutil.ErrUnauthorized or imgutil.ErrPlatformMismatch.
+func WithV1Strict() V1ImageOption {
+	return func(opts *v1Options) error {
+		opts.strict = true
+		return nil
+	}
+}
+
+type IndexOption func(*indexOptions) error
+
+type indexOptions struct {
+	baseIndexRepoName string
+	mediaTypes        imgutil.MediaTypes
+	registrySettings  map[string]RegistryConfig
+}
+
+// FromBaseIndex loads an existing index as the manifests for the new index.
+// Ignored if index is not found.
+func FromBaseIndex(indexName string) IndexOption {
+	return func(opts *indexOptions) error {
+		opts.baseIndexRepoName = indexName
+		return nil
+	}
+}
+
+// WithIndexMediaTypes lets a caller set the desired media type for the index manifest,
+// to be either an OCI image index or a Docker manifest list.
+func WithIndexMediaTypes(requested imgutil.MediaTypes) IndexOption {
+	return func(opts *indexOptions) error {
+		opts.mediaTypes = requested
+		return nil
+	}
+}
+
+// WithIndexRegistrySetting registers options to use when accessing indexes in a registry in order to construct
+// and save the index.
+// insecure parameter allows index references to be fetched without TLS.
+func WithIndexRegistrySetting(repository string, insecure bool) IndexOption {
+	return WithIndexRegistryConfig(repository, RegistryConfig{Insecure: insecure})
+}
+
+// WithIndexRegistryConfig is like WithIndexRegistrySetting, but allows the TLS, proxy and transport
+// configuration to use when accessing indexes in the registry, or repository, to be provided.
+func WithIndexRegistryConfig(repository string, config RegistryConfig) IndexOption {
+	config = config.withTransportCache()
+	return func(opts *indexOptions) error {
+		if err := config.validate(); err != nil {
+			return fmt.Errorf("invalid config for registry %q: %w", repository, err)
+		}
+		if len(opts.registrySettings) == 0 {
+			opts.registrySettings = make(map[string]RegistryConfig)
+		}
+
+		opts.registrySettings[repository] = config
+
 		return nil
 	}
 }
@@ -0,0 +1,121 @@
+package remote
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/buildpacks/imgutil"
+)
+
+// Attach pushes the artifact to the repository of the image, with the image as its subject,
+// and returns the descriptor that lists the artifact among the referrers of the image.
+// For registries without the OCI referrers API, the artifact is also added to the index
+// tagged with the digest of the image, i.e. `sha256-<hex>`.
+func (i *Image) Attach(artifact imgutil.Artifact) (v1.Descriptor, error) {
+	subject, err := partial.Descriptor(i.image)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	raw, blobs, err := imgutil.ArtifactManifest(artifact, *subject)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	desc, err := imgutil.ReferrerDescriptor(raw)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+
+	ref, auth, transport, err := i.endpoint(i.repoName)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	ctx := context.Background()
+	err = i.retryPolicy.do(ctx, transport, func(rt http.RoundTripper) error {
+		opts := append(i.retryPolicy.remoteOptions(), remote.WithAuth(auth), remote.WithTransport(rt), remote.WithContext(ctx))
+		for _, blob := range blobs {
+			if err := remote.WriteLayer(ref.Context(), blob, opts...); err != nil {
+				return err
+			}
+		}
+		return remote.Put(ref.Context().Digest(desc.Digest.String()), &manifestOnly{raw: raw, mediaType: types.OCIManifestSchema1}, opts...)
+	})
+	if err != nil {
+		return v1.Descriptor{}, registryError(err)
+	}
+	return desc, nil
+}
+
+// Referrers returns the descriptors of the artifacts attached to the image that have the given artifact type,
+// or of all of them if artifactType is empty.
+// The OCI referrers API is used when the registry supports it, and the index tagged with the digest of the image
+// otherwise.
+func (i *Image) Referrers(artifactType string) ([]v1.Descriptor, error) {
+	digest, err := i.image.Digest()
+	if err != nil {
+		return nil, err
+	}
+	ref, auth, transport, err := i.endpoint(i.repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	var referrers *v1.IndexManifest
+	ctx := context.Background()
+	err = i.retryPolicy.do(ctx, transport, func(rt http.RoundTripper) error {
+		index, err := remote.Referrers(ref.Context().Digest(digest.String()), append(i.retryPolicy.remoteOptions(),
+			remote.WithAuth(auth),
+			remote.WithTransport(rt),
+			remote.WithContext(ctx),
+		)...)
+		if err != nil {
+			return err
+		}
+		referrers, err = index.IndexManifest()
+		return err
+	})
+	if err != nil {
+		return nil, registryError(err)
+	}
+	return imgutil.FilterReferrers(referrers.Manifests, artifactType), nil
+}
+
+// ReadArtifact returns the artifact with the given descriptor, as returned by Attach or Referrers.
+func (i *Image) ReadArtifact(desc v1.Descriptor) (imgutil.Artifact, error) {
+	ref, auth, transport, err := i.endpoint(i.repoName)
+	if err != nil {
+		return imgutil.Artifact{}, err
+	}
+
+	var artifact imgutil.Artifact
+	ctx := context.Background()
+	err = i.retryPolicy.do(ctx, transport, func(rt http.RoundTripper) error {
+		opts := append(i.retryPolicy.remoteOptions(), remote.WithAuth(auth), remote.WithTransport(rt), remote.WithContext(ctx))
+		manifest, err := remote.Get(ref.Context().Digest(desc.Digest.String()), opts...)
+		if err != nil {
+			return err
+		}
+		artifact, err = imgutil.ParseArtifact(manifest.Manifest, func(blob v1.Descriptor) ([]byte, error) {
+			layer, err := remote.Layer(ref.Context().Digest(blob.Digest.String()), opts...)
+			if err != nil {
+				return nil, err
+			}
+			rc, err := layer.Compressed()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		})
+		return err
+	})
+	if err != nil {
+		return imgutil.Artifact{}, registryError(err)
+	}
+	return artifact, nil
+}
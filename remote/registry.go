@@ -0,0 +1,162 @@
+package remote
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// RegistryConfig configures how the images and indexes of a registry, or of a repository within it, are accessed.
+type RegistryConfig struct {
+	// Insecure allows the registry to be accessed over plain HTTP, and skips verifying its TLS certificate.
+	Insecure bool
+	// PlainHTTP allows the registry to be accessed over plain HTTP.
+	// Unlike Insecure, the TLS certificate of the registry is still verified when it is accessed over HTTPS.
+	PlainHTTP bool
+	// InsecureSkipVerify skips verifying the TLS certificate of the registry.
+	InsecureSkipVerify bool
+	// CABundle holds PEM encoded certificates of the authorities trusted to sign the TLS certificate of the registry,
+	// in addition to the system certificate pool.
+	CABundle []byte
+	// ClientCertificates are presented to the registry when it requests a TLS client certificate.
+	ClientCertificates []tls.Certificate
+	// Proxy returns the proxy to use for a request to the registry.
+	// If nil, the proxy is read from the environment, as for http.DefaultTransport.
+	Proxy func(*http.Request) (*url.URL, error)
+	// Transport, if set, is used to make requests to the registry, and the TLS and proxy settings above are ignored.
+	Transport http.RoundTripper
+
+	cache *transportCache // holds the transport built for the config, so that its copies reuse connections
+}
+
+// transportCache holds the transport built for a registry config.
+type transportCache struct {
+	once      sync.Once
+	transport http.RoundTripper
+	err       error
+}
+
+var errEmptyCABundle = errors.New("no PEM encoded certificates found in CA bundle")
+
+func (c RegistryConfig) validate() error {
+	if len(c.CABundle) > 0 && !x509.NewCertPool().AppendCertsFromPEM(c.CABundle) {
+		return errEmptyCABundle
+	}
+	return nil
+}
+
+// withTransportCache returns a copy of the config that builds its transport once, and shares it with its own copies.
+func (c RegistryConfig) withTransportCache() RegistryConfig {
+	c.cache = &transportCache{}
+	return c
+}
+
+// transport returns the round tripper to use for requests to the registry.
+func (c RegistryConfig) transport() (http.RoundTripper, error) {
+	if c.cache == nil {
+		return c.newTransport()
+	}
+	c.cache.once.Do(func() {
+		c.cache.transport, c.cache.err = c.newTransport()
+	})
+	return c.cache.transport, c.cache.err
+}
+
+func (c RegistryConfig) newTransport() (http.RoundTripper, error) {
+	if c.Transport != nil {
+		return c.Transport, nil
+	}
+	skipVerify := c.Insecure || c.InsecureSkipVerify
+	if !skipVerify && len(c.CABundle) == 0 && len(c.ClientCertificates) == 0 && c.Proxy == nil {
+		return http.DefaultTransport, nil
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	// #nosec G402
+	t.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: skipVerify,
+		Certificates:       c.ClientCertificates,
+	}
+	if len(c.CABundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(c.CABundle) {
+			return nil, errEmptyCABundle
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+	if c.Proxy != nil {
+		t.Proxy = c.Proxy
+	}
+	return t, nil
+}
+
+// nameOptions returns the options to use when parsing references to images in the registry.
+func (c RegistryConfig) nameOptions() []name.Option {
+	opts := []name.Option{name.WeakValidation}
+	if c.Insecure || c.PlainHTTP {
+		opts = append(opts, name.Insecure)
+	}
+	return opts
+}
+
+// getRegistry returns the config registered for the longest repository prefix of repoName,
+// or the zero config if there is none.
+func getRegistry(repoName string, registrySettings map[string]RegistryConfig) RegistryConfig {
+	var (
+		match  RegistryConfig
+		prefix string
+	)
+	for p, r := range registrySettings {
+		if strings.HasPrefix(repoName, p) && len(p) > len(prefix) {
+			match, prefix = r, p
+		}
+	}
+	return match
+}
+
+// imageSource is an endpoint to read an image from.
+type imageSource struct {
+	repoName string
+	reg      RegistryConfig
+}
+
+// readSources returns the endpoints to read the image with the given name from:
+// the mirrors registered for its registry, in order, followed by the registry itself.
+func readSources(repoName string, mirrors map[string][]string, configFor func(repoName string) RegistryConfig) []imageSource {
+	var sources []imageSource
+	if ref, err := name.ParseReference(repoName, name.WeakValidation); err == nil {
+		for _, mirror := range mirrors[ref.Context().RegistryStr()] {
+			mirrorName := mirrorRepoName(ref, mirror)
+			sources = append(sources, imageSource{repoName: mirrorName, reg: configFor(mirrorName)})
+		}
+	}
+	return append(sources, imageSource{repoName: repoName, reg: configFor(repoName)})
+}
+
+// mirrorRepoName returns the name of the image with the given reference in a mirror of its registry.
+// The mirror is a registry, optionally followed by the path that the repositories of the registry are mirrored under.
+func mirrorRepoName(ref name.Reference, mirror string) string {
+	delim := ":"
+	if _, ok := ref.(name.Digest); ok {
+		delim = "@"
+	}
+	return strings.TrimSuffix(mirror, "/") + "/" + ref.Context().RepositoryStr() + delim + ref.Identifier()
+}
+
+// mirrorsKey returns the key to register the mirrors of the given registry under.
+func mirrorsKey(registry string) (string, error) {
+	reg, err := name.NewRegistry(registry, name.WeakValidation)
+	if err != nil {
+		return "", err
+	}
+	return reg.RegistryStr(), nil
+}
@@ -1,6 +1,7 @@
 package remote
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -19,9 +20,10 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/blobcache"
 )
 
-const maxRetries = 2
+var _ imgutil.RebaseReporter = &Image{}
 
 type Image struct {
 	keychain            authn.Keychain
@@ -32,16 +34,34 @@ type Image struct {
 	createdAt           time.Time
 	addEmptyLayerOnSave bool
 	withHistory         bool
-	registrySettings    map[string]registrySetting
+	progress            imgutil.ProgressHandler
+	registrySettings    map[string]RegistryConfig
 	requestedMediaTypes imgutil.MediaTypes
-}
-
-type registrySetting struct {
-	insecure bool
+	baseImageSource     string
+	prevImageSource     string
+	retryPolicy         RetryPolicy
+	mountSources        map[v1.Hash]name.Repository // layer digest -> repository the layer can be mounted from
+	blobCache           *blobcache.Cache
 }
 
 // getters
 
+// Annotations returns a copy of the annotations in the image manifest.
+func (i *Image) Annotations() (map[string]string, error) {
+	manifest, err := i.image.Manifest()
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting manifest for image %q", i.repoName)
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("missing manifest for image %q", i.repoName)
+	}
+	annotations := make(map[string]string, len(manifest.Annotations))
+	for k, v := range manifest.Annotations {
+		annotations[k] = v
+	}
+	return annotations, nil
+}
+
 func (i *Image) Architecture() (string, error) {
 	cfg, err := i.image.ConfigFile()
 	if err != nil {
@@ -56,6 +76,18 @@ func (i *Image) Architecture() (string, error) {
 	return cfg.Architecture, nil
 }
 
+// ConfigFile returns a copy of the config file of the image.
+func (i *Image) ConfigFile() (*v1.ConfigFile, error) {
+	cfg, err := i.image.ConfigFile()
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting config file for image %q", i.repoName)
+	}
+	if cfg == nil {
+		return nil, fmt.Errorf("missing config for image %q", i.repoName)
+	}
+	return cfg.DeepCopy(), nil
+}
+
 func (i *Image) CreatedAt() (time.Time, error) {
 	configFile, err := i.image.ConfigFile()
 	if err != nil {
@@ -93,18 +125,35 @@ func (i *Image) Env(key string) (string, error) {
 }
 
 func (i *Image) Found() bool {
-	_, err := i.found()
+	return i.FoundContext(context.Background())
+}
+
+func (i *Image) FoundContext(ctx context.Context) bool {
+	_, err := i.found(ctx)
 
 	return err == nil
 }
 
-func (i *Image) found() (*v1.Descriptor, error) {
+func (i *Image) found(ctx context.Context) (*v1.Descriptor, error) {
 	reg := getRegistry(i.repoName, i.registrySettings)
-	ref, auth, err := referenceForRepoName(i.keychain, i.repoName, reg.insecure)
+	ref, auth, err := referenceForRepoName(i.keychain, i.repoName, reg)
 	if err != nil {
 		return nil, err
 	}
-	return remote.Head(ref, remote.WithAuth(auth), remote.WithTransport(getTransport(reg.insecure)))
+	transport, err := reg.transport()
+	if err != nil {
+		return nil, err
+	}
+	var desc *v1.Descriptor
+	err = i.retryPolicy.do(ctx, transport, func(rt http.RoundTripper) error {
+		desc, err = remote.Head(ref, append(i.retryPolicy.remoteOptions(),
+			remote.WithAuth(auth),
+			remote.WithTransport(rt),
+			remote.WithContext(ctx),
+		)...)
+		return err
+	})
+	return desc, err
 }
 
 func (i *Image) Valid() bool {
@@ -113,11 +162,19 @@ func (i *Image) Valid() bool {
 
 func (i *Image) valid() error {
 	reg := getRegistry(i.repoName, i.registrySettings)
-	ref, auth, err := referenceForRepoName(i.keychain, i.repoName, reg.insecure)
+	ref, auth, err := referenceForRepoName(i.keychain, i.repoName, reg)
 	if err != nil {
 		return err
 	}
-	desc, err := remote.Get(ref, remote.WithAuth(auth), remote.WithTransport(getTransport(reg.insecure)))
+	transport, err := reg.transport()
+	if err != nil {
+		return err
+	}
+	var desc *remote.Descriptor
+	err = i.retryPolicy.do(context.Background(), transport, func(rt http.RoundTripper) error {
+		desc, err = remote.Get(ref, append(i.retryPolicy.remoteOptions(), remote.WithAuth(auth), remote.WithTransport(rt))...)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -137,11 +194,19 @@ func (i *Image) valid() error {
 }
 
 func (i *Image) GetAnnotateRefName() (string, error) {
-	// TODO issue https://github.com/buildpacks/imgutil/issues/178
-	return "", errors.New("not yet implemented")
+	annotations, err := i.Annotations()
+	if err != nil {
+		return "", err
+	}
+	return annotations["org.opencontainers.image.ref.name"], nil
 }
 
 func (i *Image) GetLayer(sha string) (io.ReadCloser, error) {
+	return i.GetLayerContext(context.Background(), sha)
+}
+
+// GetLayerContext is like GetLayer, but aborts fetching the layer from the registry when ctx is done.
+func (i *Image) GetLayerContext(ctx context.Context, sha string) (io.ReadCloser, error) {
 	layers, err := i.image.Layers()
 	if err != nil {
 		return nil, err
@@ -152,7 +217,44 @@ func (i *Image) GetLayer(sha string) (io.ReadCloser, error) {
 		return nil, err
 	}
 
-	return layer.Uncompressed()
+	if mountable, ok := layer.(*remote.MountableLayer); ok {
+		// layers of the base or previous image are fetched lazily, so they need to be re-requested with ctx
+		// unless they are read from the blob cache
+		digest, err := mountable.Digest()
+		if err != nil {
+			return nil, err
+		}
+		if i.blobCache != nil && i.blobCache.Contains(digest) {
+			return layer.Uncompressed()
+		}
+		reg := getRegistry(mountable.Reference.Context().Name(), i.registrySettings)
+		_, auth, err := referenceForRepoName(i.keychain, mountable.Reference.Context().Name(), reg)
+		if err != nil {
+			return nil, err
+		}
+		transport, err := reg.transport()
+		if err != nil {
+			return nil, err
+		}
+		if layer, err = remote.Layer(mountable.Reference.Context().Digest(digest.String()),
+			remote.WithAuth(auth),
+			remote.WithTransport(transport),
+			remote.WithContext(ctx),
+		); err != nil {
+			return nil, registryError(err)
+		}
+		if i.blobCache != nil {
+			if layer, err = i.blobCache.Layer(layer); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return nil, registryError(err)
+	}
+	return rc, nil
 }
 
 func (i *Image) History() ([]v1.History, error) {
@@ -285,14 +387,18 @@ func (i *Image) WorkingDir() (string, error) {
 // setters
 
 func (i *Image) AnnotateRefName(refName string) error {
-	// TODO issue https://github.com/buildpacks/imgutil/issues/178
-	return errors.New("not yet implemented")
+	return i.SetAnnotation("org.opencontainers.image.ref.name", refName)
 }
 
 func (i *Image) Rename(name string) {
 	i.repoName = name
 }
 
+// SetAnnotation sets an annotation in the image manifest, replacing any existing value for the key.
+func (i *Image) SetAnnotation(key, value string) error {
+	return i.setAnnotations(map[string]string{key: value})
+}
+
 func (i *Image) SetArchitecture(architecture string) error {
 	configFile, err := i.image.ConfigFile()
 	if err != nil {
@@ -449,25 +555,66 @@ func (i *Image) AddLayerWithDiffIDAndHistory(path, diffID string, history v1.His
 }
 
 func (i *Image) Delete() error {
+	return i.DeleteContext(context.Background())
+}
+
+func (i *Image) DeleteContext(ctx context.Context) error {
 	id, err := i.Identifier()
 	if err != nil {
 		return err
 	}
 	reg := getRegistry(i.repoName, i.registrySettings)
-	ref, auth, err := referenceForRepoName(i.keychain, id.String(), reg.insecure)
+	ref, auth, err := referenceForRepoName(i.keychain, id.String(), reg)
 	if err != nil {
 		return err
 	}
-	return remote.Delete(ref, remote.WithAuth(auth), remote.WithTransport(getTransport(reg.insecure)))
+	transport, err := reg.transport()
+	if err != nil {
+		return err
+	}
+	return registryError(i.retryPolicy.do(ctx, transport, func(rt http.RoundTripper) error {
+		return remote.Delete(ref, append(i.retryPolicy.remoteOptions(),
+			remote.WithAuth(auth),
+			remote.WithTransport(rt),
+			remote.WithContext(ctx),
+		)...)
+	}))
+}
+
+// MutateConfigFile calls withFunc on a copy of the config file of the image, and sets the result as the config file.
+// If the mutation does not pass imgutil.ValidateConfigFileMutation, an error is returned and the image is left as is.
+func (i *Image) MutateConfigFile(withFunc func(c *v1.ConfigFile)) error {
+	configFile, err := i.ConfigFile()
+	if err != nil {
+		return err
+	}
+	before := configFile.DeepCopy()
+	withFunc(configFile)
+	if err = imgutil.ValidateConfigFileMutation(before, configFile); err != nil {
+		return err
+	}
+	i.image, err = mutate.ConfigFile(i.image, configFile)
+	return err
 }
 
 func (i *Image) Rebase(baseTopLayer string, newBase imgutil.Image) error {
+	var (
+		newBaseImage v1.Image
+		err          error
+	)
 	newBaseRemote, ok := newBase.(*Image)
-	if !ok {
-		return errors.New("expected new base to be a remote image")
+	if ok {
+		newBaseImage = newBaseRemote.image
+	} else if newBaseImage, err = imgutil.NewBaseImage(newBase); err != nil {
+		return err
 	}
 
-	newImage, err := mutate.Rebase(i.image, &subImage{img: i.image, topDiffID: baseTopLayer}, newBaseRemote.image)
+	annotations, err := i.Annotations()
+	if err != nil {
+		return err
+	}
+
+	newImage, err := mutate.Rebase(i.image, imgutil.SubImage(i.image, baseTopLayer), newBaseImage)
 	if err != nil {
 		return errors.Wrap(err, "rebase")
 	}
@@ -477,21 +624,48 @@ func (i *Image) Rebase(baseTopLayer string, newBase imgutil.Image) error {
 		return err
 	}
 
-	newBaseRemoteConfig, err := newBaseRemote.image.ConfigFile()
+	newBaseConfig, err := newBaseImage.ConfigFile()
 	if err != nil {
 		return err
 	}
 
-	newImageConfig.Architecture = newBaseRemoteConfig.Architecture
-	newImageConfig.OS = newBaseRemoteConfig.OS
-	newImageConfig.OSVersion = newBaseRemoteConfig.OSVersion
+	newImageConfig.Architecture = newBaseConfig.Architecture
+	newImageConfig.OS = newBaseConfig.OS
+	newImageConfig.OSVersion = newBaseConfig.OSVersion
 
 	newImage, err = mutate.ConfigFile(newImage, newImageConfig)
 	if err != nil {
 		return err
 	}
 
-	i.image = newImage
+	if ok {
+		for digest, source := range newBaseRemote.mountSources {
+			if i.mountSources == nil {
+				i.mountSources = make(map[v1.Hash]name.Repository)
+			}
+			i.mountSources[digest] = source
+		}
+	}
+
+	// keep the manifest annotations of the original image
+	i.image = imgutil.OverrideAnnotations(newImage, annotations)
+	return nil
+}
+
+// RebaseWithReport is like Rebase, but first validates the new base as requested and reports what the rebase changed.
+func (i *Image) RebaseWithReport(baseTopLayer string, newBase imgutil.Image, ops ...imgutil.RebaseOption) (imgutil.RebaseReport, error) {
+	return imgutil.RebaseWithReport(i, baseTopLayer, newBase, func() error {
+		return i.Rebase(baseTopLayer, newBase)
+	}, ops...)
+}
+
+func (i *Image) RemoveAnnotation(key string) error {
+	annotations, err := i.Annotations()
+	if err != nil {
+		return err
+	}
+	delete(annotations, key)
+	i.image = imgutil.OverrideAnnotations(i.image, annotations)
 	return nil
 }
 
@@ -533,7 +707,7 @@ func (i *Image) ReuseLayerWithHistory(sha string, history v1.History) error {
 
 func (i *Image) CheckReadAccess() (bool, error) {
 	var err error
-	if _, err = i.found(); err == nil {
+	if _, err = i.found(context.Background()); err == nil {
 		return true, nil
 	}
 	var canRead bool
@@ -543,7 +717,7 @@ func (i *Image) CheckReadAccess() (bool, error) {
 			err = nil
 		}
 	}
-	return canRead, err
+	return canRead, registryError(err)
 }
 
 func (i *Image) CheckReadWriteAccess() (bool, error) {
@@ -551,17 +725,39 @@ func (i *Image) CheckReadWriteAccess() (bool, error) {
 		return false, err
 	}
 	reg := getRegistry(i.repoName, i.registrySettings)
-	ref, _, err := referenceForRepoName(i.keychain, i.repoName, reg.insecure)
+	ref, _, err := referenceForRepoName(i.keychain, i.repoName, reg)
+	if err != nil {
+		return false, err
+	}
+	transport, err := reg.transport()
 	if err != nil {
 		return false, err
 	}
-	err = remote.CheckPushPermission(ref, i.keychain, http.DefaultTransport)
+	err = remote.CheckPushPermission(ref, i.keychain, transport)
 	if err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
+// BaseImageFound tells whether the image provided with FromBaseImage was found.
+// It is false if no base image was provided, or if it could not be resolved and the image was created from scratch.
+func (i *Image) BaseImageFound() bool {
+	return i.baseImageSource != ""
+}
+
+// BaseImageSource returns the name the base image was read by: the name provided with FromBaseImage,
+// or its name in the mirror that served it. It is empty if the base image was not found.
+func (i *Image) BaseImageSource() string {
+	return i.baseImageSource
+}
+
+// PreviousImageSource returns the name the previous image was read by: the name provided with WithPreviousImage,
+// or its name in the mirror that served it. It is empty if the previous image was not found.
+func (i *Image) PreviousImageSource() string {
+	return i.prevImageSource
+}
+
 // UnderlyingImage exposes the underlying image for testing
 func (i *Image) UnderlyingImage() v1.Image {
 	return i.image
@@ -569,6 +765,43 @@ func (i *Image) UnderlyingImage() v1.Image {
 
 // helpers
 
+func (i *Image) setAnnotations(annotations map[string]string) error {
+	if len(annotations) == 0 {
+		return nil
+	}
+	image, ok := mutate.Annotations(i.image, annotations).(v1.Image)
+	if !ok {
+		return fmt.Errorf("failed to set annotations for image %q", i.repoName)
+	}
+	i.image = image
+	return nil
+}
+
+// registryError returns err wrapped with the imgutil error matching the registry response that caused it, if any.
+func registryError(err error) error {
+	var transportErr *transport.Error
+	if !errors.As(err, &transportErr) {
+		return err
+	}
+	for _, diagnostic := range transportErr.Errors {
+		switch diagnostic.Code {
+		case transport.BlobUnknownErrorCode:
+			return imgutil.WrapError(imgutil.ErrLayerNotFound, err)
+		case transport.ManifestUnknownErrorCode, transport.NameUnknownErrorCode:
+			return imgutil.WrapError(imgutil.ErrImageNotFound, err)
+		}
+	}
+	switch transportErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return imgutil.WrapError(imgutil.ErrUnauthorized, err)
+	case http.StatusTooManyRequests:
+		return imgutil.WrapError(imgutil.ErrRateLimited, err)
+	case http.StatusNotFound:
+		return imgutil.WrapError(imgutil.ErrImageNotFound, err)
+	}
+	return err
+}
+
 func findLayerWithSha(layers []v1.Layer, diffID string) (v1.Layer, int, error) {
 	for idx, layer := range layers {
 		dID, err := layer.DiffID()
@@ -579,40 +812,5 @@ func findLayerWithSha(layers []v1.Layer, diffID string) (v1.Layer, int, error) {
 			return layer, idx, nil
 		}
 	}
-	return nil, -1, fmt.Errorf("previous image did not have layer with diff id %q", diffID)
+	return nil, -1, imgutil.Errorf(imgutil.ErrLayerNotFound, "previous image did not have layer with diff id %q", diffID)
 }
-
-// for rebase
-
-type subImage struct {
-	img       v1.Image
-	topDiffID string
-}
-
-func (si *subImage) Layers() ([]v1.Layer, error) {
-	all, err := si.img.Layers()
-	if err != nil {
-		return nil, err
-	}
-	for i, l := range all {
-		d, err := l.DiffID()
-		if err != nil {
-			return nil, err
-		}
-		if d.String() == si.topDiffID {
-			return all[0 : i+1], nil
-		}
-	}
-	return nil, errors.New("could not find base layer in image")
-}
-func (si *subImage) ConfigFile() (*v1.ConfigFile, error)     { return si.img.ConfigFile() }
-func (si *subImage) BlobSet() (map[v1.Hash]struct{}, error)  { panic("Not Implemented") }
-func (si *subImage) MediaType() (types.MediaType, error)     { panic("Not Implemented") }
-func (si *subImage) ConfigName() (v1.Hash, error)            { panic("Not Implemented") }
-func (si *subImage) RawConfigFile() ([]byte, error)          { panic("Not Implemented") }
-func (si *subImage) Digest() (v1.Hash, error)                { panic("Not Implemented") }
-func (si *subImage) Manifest() (*v1.Manifest, error)         { panic("Not Implemented") }
-func (si *subImage) RawManifest() ([]byte, error)            { panic("Not Implemented") }
-func (si *subImage) LayerByDigest(v1.Hash) (v1.Layer, error) { panic("Not Implemented") }
-func (si *subImage) LayerByDiffID(v1.Hash) (v1.Layer, error) { panic("Not Implemented") }
-func (si *subImage) Size() (int64, error)                    { panic("Not Implemented") }
@@ -1,12 +1,21 @@
 package remote_test
 
 import (
+	"context"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -14,10 +23,14 @@ import (
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/registry"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	ggcrremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
 
 	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/blobcache"
+	"github.com/buildpacks/imgutil/layout"
 	"github.com/buildpacks/imgutil/remote"
 	h "github.com/buildpacks/imgutil/testhelpers"
 )
@@ -71,6 +84,7 @@ func TestRemote(t *testing.T) {
 	defer os.Unsetenv("DOCKER_CONFIG")
 
 	spec.Run(t, "Image", testImage, spec.Sequential(), spec.Report(report.Terminal{}))
+	spec.Run(t, "Index", testIndex, spec.Sequential(), spec.Report(report.Terminal{}))
 }
 
 func testImage(t *testing.T, when spec.G, it spec.S) {
@@ -197,6 +211,7 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 						arch, err := img.Architecture()
 						h.AssertNil(t, err)
 						h.AssertEq(t, arch, "arm64")
+						h.AssertEq(t, img.BaseImageFound(), true)
 
 						readCloser, err := img.GetLayer(existingLayerSha)
 						h.AssertNil(t, err)
@@ -292,10 +307,25 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 						)
 
 						h.AssertNil(t, err)
+						h.AssertEq(t, img.BaseImageFound(), false)
 
 						_, err = img.TopLayer()
 						h.AssertError(t, err, "has no layers")
 					})
+
+					when("#WithStrictBaseImage", func() {
+						it("returns an error matching imgutil.ErrImageNotFound", func() {
+							_, err := remote.NewImage(
+								repoName,
+								authn.DefaultKeychain,
+								remote.FromBaseImage(newTestImageName()),
+								remote.WithStrictBaseImage(),
+							)
+
+							h.AssertError(t, err, "connect to repo store")
+							h.AssertEq(t, errors.Is(err, imgutil.ErrImageNotFound), true)
+						})
+					})
 				})
 			})
 
@@ -389,6 +419,42 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 						})
 					})
 
+					when("images for several variants of the architecture exist", func() {
+						it("returns the image matching the variant", func() {
+							indexName := newTestImageName("variant-index")
+							index, err := remote.NewIndex(indexName, authn.DefaultKeychain)
+							h.AssertNil(t, err)
+							for _, variant := range []string{"v6", "v7"} {
+								variantImage, err := remote.NewImage(newTestImageName(), authn.DefaultKeychain, remote.WithDefaultPlatform(imgutil.Platform{
+									OS:           "linux",
+									Architecture: "arm",
+									Variant:      variant,
+								}))
+								h.AssertNil(t, err)
+								h.AssertNil(t, variantImage.Save())
+								h.AssertNil(t, index.Add(variantImage))
+							}
+							h.AssertNil(t, index.Save())
+
+							img, err := remote.NewImage(
+								repoName,
+								authn.DefaultKeychain,
+								remote.FromBaseImage(indexName),
+								remote.WithDefaultPlatform(imgutil.Platform{
+									OS:           "linux",
+									Architecture: "arm",
+									Variant:      "v7",
+								}),
+							)
+							h.AssertNil(t, err)
+							h.AssertEq(t, img.BaseImageFound(), true)
+
+							variant, err := img.Variant()
+							h.AssertNil(t, err)
+							h.AssertEq(t, variant, "v7")
+						})
+					})
+
 					when("no image with matching platform exists", func() {
 						it("returns an empty image with platform fields set", func() {
 							manifestListName := "golang:1.13.8"
@@ -540,6 +606,19 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 
 					h.AssertNil(t, err)
 				})
+
+				when("#WithStrictBaseImage", func() {
+					it("returns an error matching imgutil.ErrImageNotFound", func() {
+						_, err := remote.NewImage(
+							repoName,
+							authn.DefaultKeychain,
+							remote.WithPreviousImage(newTestImageName()),
+							remote.WithStrictBaseImage(),
+						)
+
+						h.AssertEq(t, errors.Is(err, imgutil.ErrImageNotFound), true)
+					})
+				})
 			})
 		})
 
@@ -614,6 +693,510 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#Attach", func() {
+		it("attaches artifacts to the image and lists them as its referrers", func() {
+			server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			defer server.Close()
+			host := strings.TrimPrefix(server.URL, "http://")
+
+			img, err := remote.NewImage(host+"/some-image", authn.DefaultKeychain,
+				remote.WithRegistryConfig(host, remote.RegistryConfig{PlainHTTP: true}),
+			)
+			h.AssertNil(t, err)
+			h.AssertNil(t, img.Save())
+			identifier, err := img.Identifier()
+			h.AssertNil(t, err)
+
+			sbom, err := img.Attach(imgutil.Artifact{
+				ArtifactType: "application/spdx+json",
+				Blobs:        []imgutil.ArtifactBlob{{MediaType: "application/spdx+json", Data: []byte(`{"spdxVersion":"SPDX-2.3"}`)}},
+			})
+			h.AssertNil(t, err)
+			h.AssertEq(t, sbom.ArtifactType, "application/spdx+json")
+			signature, err := img.Attach(imgutil.Artifact{
+				ArtifactType: "application/vnd.dev.cosign.artifact.sig.v1+json",
+				Annotations:  map[string]string{"some-key": "some-value"},
+			})
+			h.AssertNil(t, err)
+			h.AssertEq(t, signature.Annotations["some-key"], "some-value")
+
+			referrers, err := img.Referrers("")
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(referrers), 2)
+
+			referrers, err = img.Referrers("application/vnd.dev.cosign.artifact.sig.v1+json")
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(referrers), 1)
+			h.AssertEq(t, referrers[0].Digest, signature.Digest)
+
+			ref, err := name.ParseReference(host+"/some-image@"+sbom.Digest.String(), name.Insecure)
+			h.AssertNil(t, err)
+			manifest, err := ggcrremote.Get(ref)
+			h.AssertNil(t, err)
+			subject, err := imgutil.Subject(manifest.Manifest)
+			h.AssertNil(t, err)
+			h.AssertEq(t, subject.Digest.String(), identifier.(remote.DigestIdentifier).Digest.DigestStr())
+		})
+
+		it("lists referrers from the digest tag when the registry has no referrers API", func() {
+			reg := registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile)))
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.Contains(r.URL.Path, "/referrers/") {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				reg.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+			host := strings.TrimPrefix(server.URL, "http://")
+
+			img, err := remote.NewImage(host+"/some-image", authn.DefaultKeychain,
+				remote.WithRegistryConfig(host, remote.RegistryConfig{PlainHTTP: true}),
+			)
+			h.AssertNil(t, err)
+			h.AssertNil(t, img.Save())
+
+			attached, err := img.Attach(imgutil.Artifact{ArtifactType: "application/spdx+json"})
+			h.AssertNil(t, err)
+
+			referrers, err := img.Referrers("application/spdx+json")
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(referrers), 1)
+			h.AssertEq(t, referrers[0].Digest, attached.Digest)
+		})
+
+		it("returns an error when the artifact has no type", func() {
+			img, err := remote.NewImage(newTestImageName(), authn.DefaultKeychain)
+			h.AssertNil(t, err)
+			_, err = img.Attach(imgutil.Artifact{})
+			h.AssertError(t, err, "missing artifact type")
+		})
+	})
+
+	when("#WithBlobCache", func() {
+		it("reads the layers of the base image from the cache once they were downloaded", func() {
+			server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			defer server.Close()
+			host := strings.TrimPrefix(server.URL, "http://")
+			opts := []remote.ImageOption{remote.WithRegistryConfig(host, remote.RegistryConfig{PlainHTTP: true})}
+
+			base, err := remote.NewImage(host+"/some-base", authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			layerPath, diffID, contents := h.RandomLayer(t, t.TempDir())
+			h.AssertNil(t, base.AddLayer(layerPath))
+			h.AssertNil(t, base.Save())
+
+			cache, err := blobcache.New(t.TempDir())
+			h.AssertNil(t, err)
+			img, err := remote.NewImage(host+"/some-app", authn.DefaultKeychain, append(opts,
+				remote.FromBaseImage(host+"/some-base"),
+				remote.WithBlobCache(cache),
+			)...)
+			h.AssertNil(t, err)
+
+			readLayer := func() []byte {
+				rc, err := img.GetLayer(diffID)
+				h.AssertNil(t, err)
+				defer rc.Close()
+				read, err := io.ReadAll(rc)
+				h.AssertNil(t, err)
+				return read
+			}
+			h.AssertEq(t, readLayer(), contents)
+
+			server.Close()
+			h.AssertEq(t, readLayer(), contents)
+			size, err := cache.Size()
+			h.AssertNil(t, err)
+			h.AssertTrue(t, func() bool { return size > 0 })
+		})
+	})
+
+	when("#WithRegistryConfig", func() {
+		var server *httptest.Server
+
+		it.After(func() {
+			if server != nil {
+				server.Close()
+			}
+		})
+
+		it("returns an error when the CA bundle has no certificates", func() {
+			_, err := remote.NewImage(
+				newTestImageName(),
+				authn.DefaultKeychain,
+				remote.WithRegistryConfig("some-registry.io", remote.RegistryConfig{CABundle: []byte("not a certificate")}),
+			)
+			h.AssertError(t, err, `invalid config for registry "some-registry.io": no PEM encoded certificates found in CA bundle`)
+		})
+
+		when("the registry uses a certificate signed by a custom authority", func() {
+			it.Before(func() {
+				server = httptest.NewTLSServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			})
+
+			it("trusts the authorities in the CA bundle", func() {
+				host := strings.TrimPrefix(server.URL, "https://")
+				caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+				img, err := remote.NewImage(host+"/some-image", authn.DefaultKeychain)
+				h.AssertNil(t, err)
+				h.AssertError(t, img.Save(), "certificate")
+
+				img, err = remote.NewImage(
+					host+"/some-image",
+					authn.DefaultKeychain,
+					remote.WithRegistryConfig(host, remote.RegistryConfig{CABundle: caBundle}),
+				)
+				h.AssertNil(t, err)
+				h.AssertNil(t, img.Save())
+				h.AssertEq(t, img.Found(), true)
+			})
+		})
+
+		it("reuses connections to the registry", func() {
+			var conns int32
+			server = httptest.NewUnstartedServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					atomic.AddInt32(&conns, 1)
+				}
+			}
+			server.StartTLS()
+			host := strings.TrimPrefix(server.URL, "https://")
+			caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+			registryConfig := remote.WithRegistryConfig(host, remote.RegistryConfig{CABundle: caBundle})
+
+			img, err := remote.NewImage(host+"/some-image", authn.DefaultKeychain, registryConfig)
+			h.AssertNil(t, err)
+			h.AssertNil(t, img.Save())
+			h.AssertEq(t, img.Found(), true)
+			connsAfterSave := atomic.LoadInt32(&conns)
+
+			for i := 0; i < 3; i++ {
+				img, err = remote.NewImage(host+"/some-image", authn.DefaultKeychain, registryConfig)
+				h.AssertNil(t, err)
+				h.AssertEq(t, img.Found(), true)
+			}
+			h.AssertEq(t, atomic.LoadInt32(&conns), connsAfterSave)
+		})
+
+		when("the registry is served over plain HTTP", func() {
+			it.Before(func() {
+				server = httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			})
+
+			it("accesses the registry over plain HTTP", func() {
+				host := strings.TrimPrefix(server.URL, "http://")
+
+				img, err := remote.NewImage(
+					host+"/some-image",
+					authn.DefaultKeychain,
+					remote.WithRegistryConfig(host, remote.RegistryConfig{PlainHTTP: true}),
+				)
+				h.AssertNil(t, err)
+				h.AssertNil(t, img.Save())
+				h.AssertEq(t, img.Found(), true)
+			})
+		})
+	})
+
+	when("#WithRegistryMirrors", func() {
+		var (
+			upstream, mirror         *httptest.Server
+			upstreamHost, mirrorHost string
+			opts                     []remote.ImageOption
+		)
+
+		saveImage := func(repoName, servedBy string) {
+			img, err := remote.NewImage(repoName, authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			h.AssertNil(t, img.SetLabel("served-by", servedBy))
+			h.AssertNil(t, img.Save())
+		}
+
+		it.Before(func() {
+			upstream = httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			mirror = httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			upstreamHost = strings.TrimPrefix(upstream.URL, "http://")
+			mirrorHost = strings.TrimPrefix(mirror.URL, "http://")
+			opts = []remote.ImageOption{
+				remote.WithRegistryConfig(upstreamHost, remote.RegistryConfig{PlainHTTP: true}),
+				remote.WithRegistryConfig(mirrorHost, remote.RegistryConfig{PlainHTTP: true}),
+				remote.WithRegistryMirrors(upstreamHost, mirrorHost+"/upstream"),
+			}
+			saveImage(upstreamHost+"/some-base:latest", "upstream")
+		})
+
+		it.After(func() {
+			upstream.Close()
+			mirror.Close()
+		})
+
+		when("the mirror serves the image", func() {
+			it.Before(func() {
+				saveImage(mirrorHost+"/upstream/some-base:latest", "mirror")
+			})
+
+			it("reads the base and previous images from the mirror", func() {
+				img, err := remote.NewImage(upstreamHost+"/some-app", authn.DefaultKeychain, append(opts,
+					remote.FromBaseImage(upstreamHost+"/some-base:latest"),
+					remote.WithPreviousImage(upstreamHost+"/some-base:latest"),
+				)...)
+				h.AssertNil(t, err)
+
+				label, err := img.Label("served-by")
+				h.AssertNil(t, err)
+				h.AssertEq(t, label, "mirror")
+				h.AssertEq(t, img.BaseImageSource(), mirrorHost+"/upstream/some-base:latest")
+				h.AssertEq(t, img.PreviousImageSource(), mirrorHost+"/upstream/some-base:latest")
+			})
+
+			it("saves the image to the registry named in its reference", func() {
+				img, err := remote.NewImage(upstreamHost+"/some-app", authn.DefaultKeychain, append(opts,
+					remote.FromBaseImage(upstreamHost+"/some-base:latest"),
+				)...)
+				h.AssertNil(t, err)
+				h.AssertNil(t, img.Save())
+
+				saved, err := remote.NewImage(upstreamHost+"/some-app", authn.DefaultKeychain, opts...)
+				h.AssertNil(t, err)
+				h.AssertEq(t, saved.Found(), true)
+			})
+		})
+
+		when("the mirror doesn't serve the image", func() {
+			it("falls back to the registry", func() {
+				img, err := remote.NewImage(upstreamHost+"/some-app", authn.DefaultKeychain, append(opts,
+					remote.FromBaseImage(upstreamHost+"/some-base:latest"),
+				)...)
+				h.AssertNil(t, err)
+
+				label, err := img.Label("served-by")
+				h.AssertNil(t, err)
+				h.AssertEq(t, label, "upstream")
+				h.AssertEq(t, img.BaseImageSource(), upstreamHost+"/some-base:latest")
+			})
+		})
+
+		when("the mirror is not reachable", func() {
+			it.Before(func() {
+				mirror.Close()
+			})
+
+			it("falls back to the registry", func() {
+				img, err := remote.NewImage(upstreamHost+"/some-app", authn.DefaultKeychain, append(opts,
+					remote.FromBaseImage(upstreamHost+"/some-base:latest"),
+				)...)
+				h.AssertNil(t, err)
+				h.AssertEq(t, img.BaseImageSource(), upstreamHost+"/some-base:latest")
+			})
+		})
+	})
+
+	when("#WithRetryPolicy", func() {
+		var (
+			server     *httptest.Server
+			host       string
+			failures   int32
+			failWith   int
+			retryAfter string
+			opts       []remote.ImageOption
+		)
+
+		it.Before(func() {
+			reg := registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile)))
+			failures = 0
+			retryAfter = "0"
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v2/" && atomic.AddInt32(&failures, -1) >= 0 {
+					if failWith == 0 {
+						// promise a body that is never sent
+						w.Header().Set("Content-Length", "1024")
+						w.WriteHeader(http.StatusOK)
+						return
+					}
+					w.Header().Set("Retry-After", retryAfter)
+					w.WriteHeader(failWith)
+					return
+				}
+				reg.ServeHTTP(w, r)
+			}))
+			host = strings.TrimPrefix(server.URL, "http://")
+			opts = []remote.ImageOption{
+				remote.WithRegistryConfig(host, remote.RegistryConfig{PlainHTTP: true}),
+				remote.WithRetryPolicy(remote.RetryPolicy{
+					MaxAttempts:          2,
+					InitialBackoff:       time.Millisecond,
+					RetryableStatusCodes: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+				}),
+			}
+
+			img, err := remote.NewImage(host+"/some-base", authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			h.AssertNil(t, img.Save())
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		when("the registry fails with a retryable status code", func() {
+			it.Before(func() {
+				failWith = http.StatusServiceUnavailable
+			})
+
+			it("retries reading the base image", func() {
+				atomic.StoreInt32(&failures, 1)
+				img, err := remote.NewImage(host+"/some-app", authn.DefaultKeychain, append(opts,
+					remote.FromBaseImage(host+"/some-base"),
+				)...)
+				h.AssertNil(t, err)
+				h.AssertEq(t, img.BaseImageFound(), true)
+			})
+
+			it("retries saving the image", func() {
+				img, err := remote.NewImage(host+"/some-app", authn.DefaultKeychain, opts...)
+				h.AssertNil(t, err)
+
+				atomic.StoreInt32(&failures, 1)
+				h.AssertNil(t, img.Save())
+				h.AssertEq(t, img.Found(), true)
+			})
+
+			it("retries single requests when no policy is provided", func() {
+				img, err := remote.NewImage(host+"/some-base", authn.DefaultKeychain,
+					remote.WithRegistryConfig(host, remote.RegistryConfig{PlainHTTP: true}),
+				)
+				h.AssertNil(t, err)
+
+				atomic.StoreInt32(&failures, 1)
+				h.AssertEq(t, img.Found(), true)
+			})
+
+			it("fails once the attempts are exhausted", func() {
+				img, err := remote.NewImage(host+"/some-base", authn.DefaultKeychain, opts...)
+				h.AssertNil(t, err)
+
+				atomic.StoreInt32(&failures, 2)
+				h.AssertEq(t, img.Found(), false)
+			})
+		})
+
+		when("the registry closes the connection in the middle of a response", func() {
+			it.Before(func() {
+				failWith = 0
+			})
+
+			it("retries reading the base image when no policy is provided", func() {
+				atomic.StoreInt32(&failures, 1)
+				img, err := remote.NewImage(host+"/some-app", authn.DefaultKeychain,
+					remote.WithRegistryConfig(host, remote.RegistryConfig{PlainHTTP: true}),
+					remote.FromBaseImage(host+"/some-base"),
+					remote.WithStrictBaseImage(),
+				)
+				h.AssertNil(t, err)
+				h.AssertEq(t, img.BaseImageFound(), true)
+			})
+		})
+
+		when("the registry rate limits requests", func() {
+			it.Before(func() {
+				failWith = http.StatusTooManyRequests
+			})
+
+			it("retries after the time requested by the registry", func() {
+				img, err := remote.NewImage(host+"/some-base", authn.DefaultKeychain, opts...)
+				h.AssertNil(t, err)
+
+				atomic.StoreInt32(&failures, 1)
+				h.AssertEq(t, img.Found(), true)
+				h.AssertEq(t, img.Valid(), true)
+			})
+
+			it("waits no longer than the maximum backoff", func() {
+				img, err := remote.NewImage(host+"/some-base", authn.DefaultKeychain, append(opts,
+					remote.WithRetryPolicy(remote.RetryPolicy{
+						MaxAttempts:          2,
+						MaxBackoff:           time.Millisecond,
+						RetryableStatusCodes: []int{http.StatusTooManyRequests},
+					}),
+				)...)
+				h.AssertNil(t, err)
+
+				retryAfter = "3600"
+				atomic.StoreInt32(&failures, 1)
+				start := time.Now()
+				h.AssertEq(t, img.Found(), true)
+				h.AssertTrue(t, func() bool { return time.Since(start) < time.Minute })
+			})
+
+			it("returns a rate limited error when retries are disabled", func() {
+				img, err := remote.NewImage(host+"/some-base", authn.DefaultKeychain, append(opts,
+					remote.WithRetryPolicy(remote.RetryPolicy{MaxAttempts: 1}),
+				)...)
+				h.AssertNil(t, err)
+
+				atomic.StoreInt32(&failures, 1)
+				err = img.Delete()
+				h.AssertError(t, err, "429")
+				h.AssertEq(t, errors.Is(err, imgutil.ErrRateLimited), true)
+			})
+		})
+	})
+
+	when("#AnnotateRefName", func() {
+		it("sets the ref name annotation on the saved manifest", func() {
+			img, err := remote.NewImage(repoName, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, img.AnnotateRefName("my-tag"))
+			h.AssertNil(t, img.Save())
+
+			saved, err := remote.NewImage(repoName, authn.DefaultKeychain, remote.FromBaseImage(repoName))
+			h.AssertNil(t, err)
+			refName, err := saved.GetAnnotateRefName()
+			h.AssertNil(t, err)
+			h.AssertEq(t, refName, "my-tag")
+		})
+	})
+
+	when("#SetAnnotation", func() {
+		it("sets annotations on the saved manifest", func() {
+			img, err := remote.NewImage(repoName, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, img.SetAnnotation("some-key", "some-value"))
+			h.AssertNil(t, img.SetAnnotation("some-other-key", "some-other-value"))
+			h.AssertNil(t, img.Save())
+
+			saved, err := remote.NewImage(repoName, authn.DefaultKeychain, remote.FromBaseImage(repoName))
+			h.AssertNil(t, err)
+			annotations, err := saved.Annotations()
+			h.AssertNil(t, err)
+			h.AssertEq(t, annotations["some-key"], "some-value")
+			h.AssertEq(t, annotations["some-other-key"], "some-other-value")
+		})
+	})
+
+	when("#RemoveAnnotation", func() {
+		it("removes annotations from the saved manifest", func() {
+			img, err := remote.NewImage(repoName, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+			h.AssertNil(t, img.SetAnnotation("some-key", "some-value"))
+			h.AssertNil(t, img.SetAnnotation("some-other-key", "some-other-value"))
+
+			h.AssertNil(t, img.RemoveAnnotation("some-key"))
+			h.AssertNil(t, img.Save())
+
+			saved, err := remote.NewImage(repoName, authn.DefaultKeychain, remote.FromBaseImage(repoName))
+			h.AssertNil(t, err)
+			annotations, err := saved.Annotations()
+			h.AssertNil(t, err)
+			h.AssertEq(t, annotations, map[string]string{"some-other-key": "some-other-value"})
+		})
+	})
+
 	when("#WorkingDir", func() {
 		when("image exists", func() {
 			var repoName = newTestImageName()
@@ -1099,6 +1682,54 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#MutateConfigFile", func() {
+		it("sets the config file", func() {
+			img, err := remote.NewImage(repoName, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+
+			err = img.MutateConfigFile(func(c *v1.ConfigFile) {
+				c.Config.User = "some-user"
+				c.Config.ExposedPorts = map[string]struct{}{"8080/tcp": {}}
+				c.Config.StopSignal = "SIGKILL"
+			})
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, img.Save())
+
+			configFile := h.FetchManifestImageConfigFile(t, repoName)
+			h.AssertEq(t, configFile.Config.User, "some-user")
+			h.AssertEq(t, configFile.Config.ExposedPorts, map[string]struct{}{"8080/tcp": {}})
+			h.AssertEq(t, configFile.Config.StopSignal, "SIGKILL")
+		})
+
+		it("errors when the layers or the number of history entries are changed", func() {
+			img, err := remote.NewImage(repoName, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+			layerPath, err := h.CreateSingleFileLayerTar("/some-file.txt", "some-content", "linux")
+			h.AssertNil(t, err)
+			defer os.Remove(layerPath)
+			h.AssertNil(t, img.AddLayer(layerPath))
+
+			err = img.MutateConfigFile(func(c *v1.ConfigFile) {
+				c.Config.User = "some-user"
+				c.RootFS.DiffIDs = c.RootFS.DiffIDs[:len(c.RootFS.DiffIDs)-1]
+			})
+			h.AssertEq(t, errors.Is(err, imgutil.ErrNotImplemented), true)
+
+			err = img.MutateConfigFile(func(c *v1.ConfigFile) {
+				c.Config.User = "some-user"
+				c.History = append(c.History, v1.History{CreatedBy: "some-history"})
+			})
+			h.AssertError(t, err, "invalid history")
+
+			// the image is left as is
+			configFile, err := img.ConfigFile()
+			h.AssertNil(t, err)
+			h.AssertEq(t, configFile.Config.User, "")
+			h.AssertEq(t, len(configFile.RootFS.DiffIDs), 1)
+		})
+	})
+
 	when("#SetEntrypoint", func() {
 		it("sets the entrypoint", func() {
 			img, err := remote.NewImage(repoName, authn.DefaultKeychain)
@@ -1258,6 +1889,147 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 				h.AssertEq(t, rebasedImgConfig.Architecture, newBaseConfig.Architecture)
 			})
 		})
+
+		when("the new base is in an OCI layout", func() {
+			it("switches the base and pushes the layers of the new base on save", func() {
+				server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+				defer server.Close()
+				host := strings.TrimPrefix(server.URL, "http://")
+				opts := []remote.ImageOption{remote.WithRegistryConfig(host, remote.RegistryConfig{PlainHTTP: true})}
+
+				oldBaseLayerPath, err := h.CreateSingleFileLayerTar("/old-base.txt", "old-base", "linux")
+				h.AssertNil(t, err)
+				defer os.Remove(oldBaseLayerPath)
+				img, err := remote.NewImage(host+"/some-app", authn.DefaultKeychain, opts...)
+				h.AssertNil(t, err)
+				h.AssertNil(t, img.AddLayer(oldBaseLayerPath))
+				oldTopLayer, err := img.TopLayer()
+				h.AssertNil(t, err)
+				appLayerPath, err := h.CreateSingleFileLayerTar("/app.txt", "app", "linux")
+				h.AssertNil(t, err)
+				defer os.Remove(appLayerPath)
+				h.AssertNil(t, img.AddLayer(appLayerPath))
+				appLayer, err := img.TopLayer()
+				h.AssertNil(t, err)
+
+				newBaseLayerPath, err := h.CreateSingleFileLayerTar("/new-base.txt", "new-base", "linux")
+				h.AssertNil(t, err)
+				defer os.Remove(newBaseLayerPath)
+				newBase, err := layout.NewImage(filepath.Join(t.TempDir(), "new-base"))
+				h.AssertNil(t, err)
+				h.AssertNil(t, newBase.AddLayer(newBaseLayerPath))
+				h.AssertNil(t, newBase.Save())
+				newBaseTopLayer, err := newBase.TopLayer()
+				h.AssertNil(t, err)
+
+				h.AssertNil(t, img.Rebase(oldTopLayer, newBase))
+				h.AssertNil(t, img.Save())
+
+				pushed, err := remote.NewImage(host+"/some-app", authn.DefaultKeychain, append(opts, remote.FromBaseImage(host+"/some-app"))...)
+				h.AssertNil(t, err)
+				configFile, err := pushed.UnderlyingImage().ConfigFile()
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(configFile.RootFS.DiffIDs), 2)
+				h.AssertEq(t, configFile.RootFS.DiffIDs[0].String(), newBaseTopLayer)
+				h.AssertEq(t, configFile.RootFS.DiffIDs[1].String(), appLayer)
+				h.AssertEq(t, pushed.Valid(), true)
+			})
+
+			it("keeps the manifest annotations of the image", func() {
+				img, err := remote.NewImage(newTestImageName(), authn.DefaultKeychain)
+				h.AssertNil(t, err)
+				h.AssertNil(t, img.SetAnnotation("org.example.app", "app"))
+				oldBaseLayerPath, err := h.CreateSingleFileLayerTar("/old-base.txt", "old-base", "linux")
+				h.AssertNil(t, err)
+				defer os.Remove(oldBaseLayerPath)
+				h.AssertNil(t, img.AddLayer(oldBaseLayerPath))
+				oldTopLayer, err := img.TopLayer()
+				h.AssertNil(t, err)
+
+				newBase, err := layout.NewImage(filepath.Join(t.TempDir(), "new-base"))
+				h.AssertNil(t, err)
+				h.AssertNil(t, newBase.SetAnnotation("org.example.base", "base"))
+
+				h.AssertNil(t, img.Rebase(oldTopLayer, newBase))
+
+				annotations, err := img.Annotations()
+				h.AssertNil(t, err)
+				h.AssertEq(t, annotations, map[string]string{"org.example.app": "app"})
+			})
+		})
+	})
+
+	when("#RebaseWithReport", func() {
+		it("validates the new base and reports the changes", func() {
+			server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			defer server.Close()
+			host := strings.TrimPrefix(server.URL, "http://")
+			opts := []remote.ImageOption{remote.WithRegistryConfig(host, remote.RegistryConfig{PlainHTTP: true})}
+
+			oldBaseLayerPath, err := h.CreateSingleFileLayerTar("/old-base.txt", "old-base", "linux")
+			h.AssertNil(t, err)
+			defer os.Remove(oldBaseLayerPath)
+			img, err := remote.NewImage(host+"/some-app", authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			h.AssertNil(t, img.AddLayer(oldBaseLayerPath))
+			oldTopLayer, err := img.TopLayer()
+			h.AssertNil(t, err)
+			appLayerPath, err := h.CreateSingleFileLayerTar("/app.txt", "app", "linux")
+			h.AssertNil(t, err)
+			defer os.Remove(appLayerPath)
+			h.AssertNil(t, img.AddLayer(appLayerPath))
+
+			newBaseLayerPath, err := h.CreateSingleFileLayerTar("/new-base.txt", "new-base", "linux")
+			h.AssertNil(t, err)
+			defer os.Remove(newBaseLayerPath)
+			newBase, err := remote.NewImage(host+"/new-base", authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			h.AssertNil(t, newBase.AddLayer(newBaseLayerPath))
+			h.AssertNil(t, newBase.SetOSVersion("some-os-version"))
+			h.AssertNil(t, newBase.SetArchitecture("arm64"))
+			newBaseTopLayer, err := newBase.TopLayer()
+			h.AssertNil(t, err)
+
+			_, err = img.RebaseWithReport(oldTopLayer, newBase, imgutil.WithPlatformValidation())
+			h.AssertError(t, err, `has architecture "arm64", expected "amd64"`)
+			h.AssertEq(t, errors.Is(err, imgutil.ErrIncompatibleBase), true)
+
+			report, err := img.RebaseWithReport(oldTopLayer, newBase)
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(report.RemovedBaseLayers), 1)
+			h.AssertEq(t, report.RemovedBaseLayers[0].String(), oldTopLayer)
+			h.AssertEq(t, len(report.AddedBaseLayers), 1)
+			h.AssertEq(t, report.AddedBaseLayers[0].String(), newBaseTopLayer)
+			h.AssertEq(t, report.ChangedConfig, []imgutil.ConfigChange{
+				{Field: "Architecture", Old: "amd64", New: "arm64"},
+				{Field: "OSVersion", Old: "", New: "some-os-version"},
+			})
+		})
+
+		it("reports the changes when the new base has no OS", func() {
+			server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			defer server.Close()
+			host := strings.TrimPrefix(server.URL, "http://")
+			opts := []remote.ImageOption{remote.WithRegistryConfig(host, remote.RegistryConfig{PlainHTTP: true})}
+
+			oldBaseLayerPath, err := h.CreateSingleFileLayerTar("/old-base.txt", "old-base", "linux")
+			h.AssertNil(t, err)
+			defer os.Remove(oldBaseLayerPath)
+			img, err := remote.NewImage(host+"/some-app", authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			h.AssertNil(t, img.AddLayer(oldBaseLayerPath))
+			oldTopLayer, err := img.TopLayer()
+			h.AssertNil(t, err)
+
+			newBase, err := remote.NewImage(host+"/new-base", authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			h.AssertNil(t, newBase.SetOS(""))
+
+			report, err := img.RebaseWithReport(oldTopLayer, newBase)
+			h.AssertNil(t, err)
+			h.AssertEq(t, report.ChangedConfig, []imgutil.ConfigChange{{Field: "OS", Old: "linux", New: ""}})
+			h.AssertEq(t, len(report.AddedBaseLayers), 0)
+		})
 	})
 
 	when("#TopLayer", func() {
@@ -1526,6 +2298,7 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 				err = img.ReuseLayer("some-bad-sha")
 
 				h.AssertError(t, err, `previous image did not have layer with diff id "some-bad-sha"`)
+				h.AssertEq(t, errors.Is(err, imgutil.ErrLayerNotFound), true)
 			})
 
 			when("there is history", func() {
@@ -1734,6 +2507,60 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 				}
 			})
 
+			when("the WithProgress option is used", func() {
+				it("reports the progress of uploading each layer and the layers that already exist", func() {
+					var (
+						mu           sync.Mutex
+						updates      []imgutil.Progress
+						layerUpdates []imgutil.Progress
+					)
+					img, err := remote.NewImage(repoName, authn.DefaultKeychain,
+						remote.WithProgress(func(p imgutil.Progress) {
+							mu.Lock()
+							defer mu.Unlock()
+							if p.Digest == (v1.Hash{}) {
+								updates = append(updates, p)
+								return
+							}
+							layerUpdates = append(layerUpdates, p)
+						}),
+					)
+					h.AssertNil(t, err)
+
+					tarPath, err := h.CreateSingleFileLayerTar("/new-layer.txt", h.RandString(10), "linux")
+					h.AssertNil(t, err)
+					defer os.Remove(tarPath)
+					h.AssertNil(t, img.AddLayer(tarPath))
+
+					h.AssertNil(t, img.Save())
+
+					diffID, err := v1.NewHash(h.FileDiffID(t, tarPath))
+					h.AssertNil(t, err)
+					layer, err := img.UnderlyingImage().LayerByDiffID(diffID)
+					h.AssertNil(t, err)
+					digest, err := layer.Digest()
+					h.AssertNil(t, err)
+					size, err := layer.Size()
+					h.AssertNil(t, err)
+					h.AssertTrue(t, func() bool { return len(layerUpdates) > 0 })
+					for _, update := range layerUpdates {
+						h.AssertEq(t, update.Digest, digest)
+						h.AssertEq(t, update.Phase, imgutil.PhaseUploading)
+						h.AssertEq(t, update.Total, size)
+					}
+					h.AssertEq(t, layerUpdates[len(layerUpdates)-1].Complete, size)
+					last := updates[len(updates)-1]
+					h.AssertEq(t, last.Complete, last.Total)
+					h.AssertTrue(t, func() bool { return last.Total >= size })
+
+					// change the manifest, so that the layers are checked again
+					layerUpdates = nil
+					h.AssertNil(t, img.SetLabel("some-key", "some-value"))
+					h.AssertNil(t, img.Save())
+					h.AssertEq(t, layerUpdates, []imgutil.Progress{{Digest: digest, Complete: size, Total: size, Phase: imgutil.PhaseExists}})
+				})
+			})
+
 			when("the WithCreatedAt option is used", func() {
 				it("uses the value for all times and client specific fields", func() {
 					expectedTime := time.Date(2022, 1, 5, 5, 5, 5, 0, time.UTC)
@@ -1813,6 +2640,207 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#SaveFile", func() {
+		it("writes a docker archive tagged with the image name", func() {
+			baseImage, err := remote.NewImage(repoName, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+			layerPath, err := h.CreateSingleFileLayerTar("/base.txt", "base", "linux")
+			h.AssertNil(t, err)
+			defer os.Remove(layerPath)
+			h.AssertNil(t, baseImage.AddLayer(layerPath))
+			h.AssertNil(t, baseImage.Save())
+
+			img, err := remote.NewImage(repoName, authn.DefaultKeychain, remote.FromBaseImage(repoName))
+			h.AssertNil(t, err)
+			appLayerPath, err := h.CreateSingleFileLayerTar("/app.txt", "app", "linux")
+			h.AssertNil(t, err)
+			defer os.Remove(appLayerPath)
+			h.AssertNil(t, img.AddLayer(appLayerPath))
+
+			path, err := img.SaveFile()
+			h.AssertNil(t, err)
+			defer os.Remove(path)
+
+			tag, err := name.NewTag(repoName, name.WeakValidation)
+			h.AssertNil(t, err)
+			saved, err := tarball.ImageFromPath(path, &tag)
+			h.AssertNil(t, err)
+
+			savedConfig, err := saved.ConfigFile()
+			h.AssertNil(t, err)
+			h.AssertEq(t, savedConfig.RootFS.DiffIDs[0].String(), h.FileDiffID(t, layerPath))
+			h.AssertEq(t, savedConfig.RootFS.DiffIDs[1].String(), h.FileDiffID(t, appLayerPath))
+		})
+	})
+
+	when("#SaveWithResult", func() {
+		it("returns the manifest pushed for each name", func() {
+			img, err := remote.NewImage(repoName, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+			h.AssertNil(t, img.SetLabel("mykey", "newValue"))
+
+			additionalName := newTestImageName()
+			results, err := img.SaveWithResult(additionalName)
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(results), 2)
+
+			digest, err := img.UnderlyingImage().Digest()
+			h.AssertNil(t, err)
+			mediaType, err := img.UnderlyingImage().MediaType()
+			h.AssertNil(t, err)
+			configName, err := img.UnderlyingImage().ConfigName()
+			h.AssertNil(t, err)
+			for idx, name := range []string{repoName, additionalName} {
+				h.AssertEq(t, results[idx].Name, name)
+				h.AssertEq(t, results[idx].Digest, digest)
+				h.AssertEq(t, results[idx].MediaType, mediaType)
+				h.AssertEq(t, results[idx].ImageID, configName.String())
+				h.AssertNil(t, results[idx].Err)
+
+				pushed, err := remote.NewImage(name+"@"+digest.String(), authn.DefaultKeychain)
+				h.AssertNil(t, err)
+				h.AssertEq(t, pushed.Found(), true)
+			}
+		})
+	})
+
+	when("#SaveWithResult", func() {
+		var (
+			server *httptest.Server
+			host   string
+			opts   []remote.ImageOption
+		)
+
+		layerDigests := func(img *remote.Image) []v1.Hash {
+			layers, err := img.UnderlyingImage().Layers()
+			h.AssertNil(t, err)
+			var digests []v1.Hash
+			for _, layer := range layers {
+				digest, err := layer.Digest()
+				h.AssertNil(t, err)
+				digests = append(digests, digest)
+			}
+			return digests
+		}
+
+		saveImage := func(repoName string, opts ...remote.ImageOption) *remote.Image {
+			img, err := remote.NewImage(repoName, authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			layerPath, _, _ := h.RandomLayer(t, t.TempDir())
+			h.AssertNil(t, img.AddLayer(layerPath))
+			h.AssertNil(t, img.Save())
+			return img
+		}
+
+		it.Before(func() {
+			server = httptest.NewServer(newRepositoryScopedRegistry())
+			host = strings.TrimPrefix(server.URL, "http://")
+			opts = []remote.ImageOption{remote.WithRegistryConfig(host, remote.RegistryConfig{PlainHTTP: true})}
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		it("mounts the layers of the base and previous images from their repositories", func() {
+			base := saveImage(host+"/some-base", opts...)
+			prev := saveImage(host+"/some-previous", opts...)
+			prevLayer, err := prev.TopLayer()
+			h.AssertNil(t, err)
+
+			img, err := remote.NewImage(host+"/some-app", authn.DefaultKeychain, append(opts,
+				remote.FromBaseImage(host+"/some-base"),
+				remote.WithPreviousImage(host+"/some-previous"),
+			)...)
+			h.AssertNil(t, err)
+			h.AssertNil(t, img.ReuseLayer(prevLayer))
+			appLayerPath, _, _ := h.RandomLayer(t, t.TempDir())
+			h.AssertNil(t, img.AddLayer(appLayerPath))
+
+			results, err := img.SaveWithResult()
+			h.AssertNil(t, err)
+			h.AssertEq(t, results[0].MountedLayers, append(layerDigests(base), layerDigests(prev)...))
+			h.AssertEq(t, results[0].UploadedLayers, layerDigests(img)[2:])
+		})
+
+		it("pushes the content once and saves the additional names without pushing it again", func() {
+			img, err := remote.NewImage(host+"/some-app", authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			layerPath, _, _ := h.RandomLayer(t, t.TempDir())
+			h.AssertNil(t, img.AddLayer(layerPath))
+
+			results, err := img.SaveWithResult(host+"/some-app:other-tag", host+"/some-other-app", "localhost:1/some-app")
+			var saveErr imgutil.SaveError
+			h.AssertEq(t, errors.As(err, &saveErr), true)
+			h.AssertEq(t, len(saveErr.Errors), 1)
+			h.AssertEq(t, saveErr.Errors[0].ImageName, "localhost:1/some-app")
+			h.AssertEq(t, len(results), 4)
+
+			h.AssertEq(t, results[0].UploadedLayers, layerDigests(img))
+			h.AssertEq(t, len(results[1].MountedLayers)+len(results[1].UploadedLayers), 0)
+			h.AssertEq(t, results[2].MountedLayers, layerDigests(img))
+			h.AssertEq(t, len(results[2].UploadedLayers), 0)
+			h.AssertEq(t, results[3].Err, saveErr.Errors[0].Cause)
+
+			for _, result := range results[:3] {
+				h.AssertNil(t, result.Err)
+				saved, err := remote.NewImage(result.Name, authn.DefaultKeychain, opts...)
+				h.AssertNil(t, err)
+				h.AssertEq(t, saved.Found(), true)
+				h.AssertEq(t, saved.Valid(), true)
+			}
+		})
+
+		it("mounts the layers of a base image read from a mirror from the mirrored repository", func() {
+			mirror := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			defer mirror.Close()
+			mirrorHost := strings.TrimPrefix(mirror.URL, "http://")
+			opts = append(opts,
+				remote.WithRegistryConfig(mirrorHost, remote.RegistryConfig{PlainHTTP: true}),
+				remote.WithRegistryMirrors(host, mirrorHost),
+			)
+			base := saveImage(host+"/some-base", opts...)
+			mirrored, err := remote.NewImage(mirrorHost+"/some-base", authn.DefaultKeychain, append(opts,
+				remote.FromBaseImage(host+"/some-base"),
+			)...)
+			h.AssertNil(t, err)
+			h.AssertNil(t, mirrored.Save())
+
+			img, err := remote.NewImage(host+"/some-app", authn.DefaultKeychain, append(opts,
+				remote.FromBaseImage(host+"/some-base"),
+			)...)
+			h.AssertNil(t, err)
+			h.AssertEq(t, img.BaseImageSource(), mirrorHost+"/some-base:latest")
+
+			results, err := img.SaveWithResult()
+			h.AssertNil(t, err)
+			h.AssertEq(t, results[0].MountedLayers, layerDigests(base))
+			h.AssertEq(t, len(results[0].UploadedLayers), 0)
+		})
+	})
+
+	when("#SaveContext", func() {
+		it("saves the image when the context is not done", func() {
+			img, err := remote.NewImage(repoName, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, img.SaveContext(context.Background()))
+			h.AssertEq(t, img.FoundContext(context.Background()), true)
+		})
+
+		it("returns the context error without pushing the image when the context is done", func() {
+			img, err := remote.NewImage(repoName, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err = img.SaveContext(ctx)
+			h.AssertTrue(t, func() bool { return errors.Is(err, context.Canceled) })
+			h.AssertEq(t, img.Found(), false)
+		})
+	})
+
 	when("#Found", func() {
 		when("it exists", func() {
 			it("returns true, nil", func() {
@@ -1975,6 +3003,13 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 				canRead, _ := image.CheckReadAccess()
 				h.AssertEq(t, canRead, false)
 			})
+
+			it("returns an error matching imgutil.ErrUnauthorized", func() {
+				image, err := remote.NewImage(repoName, authn.DefaultKeychain)
+				h.AssertNil(t, err)
+				_, err = image.CheckReadAccess()
+				h.AssertEq(t, errors.Is(err, imgutil.ErrUnauthorized), true)
+			})
 		})
 
 		when("using custom handler", func() {
@@ -2135,3 +3170,40 @@ func testImage(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 }
+
+// newRepositoryScopedRegistry returns a registry that, unlike registry.New, only serves the blobs of a repository
+// that were pushed to it or mounted into it, so that cross-repository mounts can be observed.
+func newRepositoryScopedRegistry() http.Handler {
+	inner := registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile)))
+	var (
+		mu    sync.Mutex
+		blobs = map[string]bool{} // repository + "@" + digest -> present
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repo, rest, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/blobs/")
+		if !ok {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Query().Get("mount") != "":
+			digest := r.URL.Query().Get("mount")
+			if blobs[r.URL.Query().Get("from")+"@"+digest] {
+				blobs[repo+"@"+digest] = true
+				w.Header().Set("Location", "/v2/"+repo+"/blobs/"+digest)
+				w.WriteHeader(http.StatusCreated)
+				return
+			}
+		case r.Method == http.MethodPut && r.URL.Query().Get("digest") != "":
+			blobs[repo+"@"+r.URL.Query().Get("digest")] = true
+		case r.Method == http.MethodHead || r.Method == http.MethodGet:
+			if !blobs[repo+"@"+rest] {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		}
+		inner.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,201 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// RetryPolicy configures how operations against a registry are retried when they fail with a transient error,
+// i.e. a network error or a response with one of the retryable status codes.
+// The zero value, which is used when no policy is provided, leaves it to the registry client to retry single requests,
+// and attempts operations up to three times when they fail with an EOF or a transient network error.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times an operation is attempted, including the first attempt.
+	// When it is at least 1, the registry client doesn't retry single requests, as operations are retried as a whole.
+	MaxAttempts int
+	// InitialBackoff is the time to wait before the first retry. It doubles after every retry, up to MaxBackoff.
+	// When the registry responds with a Retry-After header, the time it asks for is waited instead, up to MaxBackoff.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum time to wait between attempts, or no maximum if it is 0.
+	MaxBackoff time.Duration
+	// Jitter is the fraction, between 0 and 1, of each backoff that is randomized.
+	Jitter float64
+	// RetryableStatusCodes are the status codes of registry responses that cause an operation to be retried.
+	RetryableStatusCodes []int
+}
+
+// DefaultRetryPolicy returns a policy to provide with WithRetryPolicy: three attempts, with an exponential backoff
+// starting at 500ms, for network errors and responses with the status codes 408, 429, 500, 502, 503 and 504.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Jitter:         0.1,
+		RetryableStatusCodes: []int{
+			http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// defaultMaxAttempts and defaultBackoff are used by the zero policy, which retries operations that fail with an error
+// that is not a response of the registry, as single requests are retried by the registry client.
+const (
+	defaultMaxAttempts = 3
+	defaultBackoff     = 100 * time.Millisecond
+)
+
+// remoteOptions returns the options that turn off the retries of the underlying registry client,
+// when operations are retried as a whole according to the policy.
+func (p RetryPolicy) remoteOptions() []remote.Option {
+	if p.MaxAttempts < 1 {
+		return nil
+	}
+	return []remote.Option{
+		remote.WithRetryStatusCodes(),
+		remote.WithRetryBackoff(remote.Backoff{Steps: 1}),
+	}
+}
+
+// do calls op until it succeeds, fails with an error that is not retryable, the attempts are exhausted or ctx is done.
+// op is called with a round tripper based on rt, which it should use to make requests to the registry.
+func (p RetryPolicy) do(ctx context.Context, rt http.RoundTripper, op func(rt http.RoundTripper) error) error {
+	recorder := &retryAfterRecorder{inner: rt, codes: p.RetryableStatusCodes}
+	maxAttempts, backoff := p.MaxAttempts, p.InitialBackoff
+	if maxAttempts < 1 {
+		maxAttempts, backoff = defaultMaxAttempts, defaultBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		recorder.reset()
+		err := op(recorder)
+		if err == nil || attempt >= maxAttempts || !p.retryable(err) {
+			return err
+		}
+
+		wait := recorder.retryAfter()
+		if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+			wait = p.MaxBackoff
+		}
+		if wait == 0 {
+			wait = p.jittered(backoff)
+			backoff *= 2
+			if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+				backoff = p.MaxBackoff
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var transportErr *transport.Error
+	if errors.As(err, &transportErr) {
+		for _, code := range p.RetryableStatusCodes {
+			if transportErr.StatusCode == code {
+				return true
+			}
+		}
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+func (p RetryPolicy) jittered(backoff time.Duration) time.Duration {
+	if p.Jitter <= 0 || backoff <= 0 {
+		return backoff
+	}
+	// #nosec G404 -- the jitter doesn't need a secure source of randomness
+	return backoff + time.Duration(rand.Float64()*p.Jitter*float64(backoff))
+}
+
+// retryAfterRecorder records the time that the registry asked to wait for with a Retry-After header,
+// in the last response with a retryable status code.
+type retryAfterRecorder struct {
+	inner http.RoundTripper
+	codes []int
+
+	mu   sync.Mutex
+	wait time.Duration
+}
+
+func (r *retryAfterRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.inner.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	for _, code := range r.codes {
+		if resp.StatusCode == code {
+			if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				r.mu.Lock()
+				r.wait = wait
+				r.mu.Unlock()
+			}
+			break
+		}
+	}
+	return resp, nil
+}
+
+func (r *retryAfterRecorder) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.wait = 0
+}
+
+func (r *retryAfterRecorder) retryAfter() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.wait
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	wait := time.Until(date)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
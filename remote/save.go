@@ -1,26 +1,112 @@
 package remote
 
 import (
-	"crypto/tls"
+	"context"
 	"fmt"
 	"net/http"
 
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/static"
 	"github.com/google/go-containerregistry/pkg/v1/types"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/buildpacks/imgutil"
 )
 
 func (i *Image) Save(additionalNames ...string) error {
-	return i.SaveAs(i.Name(), additionalNames...)
+	return i.SaveAsContext(context.Background(), i.Name(), additionalNames...)
+}
+
+func (i *Image) SaveContext(ctx context.Context, additionalNames ...string) error {
+	return i.SaveAsContext(ctx, i.Name(), additionalNames...)
 }
 
 func (i *Image) SaveAs(name string, additionalNames ...string) error {
+	return i.SaveAsContext(context.Background(), name, additionalNames...)
+}
+
+// SaveAsContext is like SaveAs, but aborts any in-flight push when ctx is done.
+// If ctx is done before all names are written, the context error is returned.
+func (i *Image) SaveAsContext(ctx context.Context, name string, additionalNames ...string) error {
+	_, err := i.saveAs(ctx, name, additionalNames...)
+	return err
+}
+
+func (i *Image) SaveWithResult(additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return i.saveAs(context.Background(), i.Name(), additionalNames...)
+}
+
+func (i *Image) SaveAsWithResult(name string, additionalNames ...string) ([]imgutil.SaveResult, error) {
+	return i.saveAs(context.Background(), name, additionalNames...)
+}
+
+// saveAs pushes the content of the image with the first name that it can be saved as,
+// and then saves it as every other name in parallel, by only putting its manifest when the content is already
+// in the repository, or mounting the content first when it is in another repository of the same registry.
+func (i *Image) saveAs(ctx context.Context, imageName string, additionalNames ...string) ([]imgutil.SaveResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := i.prepareForSave(); err != nil {
+		return nil, err
+	}
+	return i.push(ctx, append([]string{imageName}, additionalNames...))
+}
+
+// push pushes the image as is with each of the given names.
+func (i *Image) push(ctx context.Context, allNames []string) ([]imgutil.SaveResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	saved, err := imgutil.NewSaveResult("", i.image)
+	if err != nil {
+		return nil, err
+	}
+
+	// save
+	results := make([]imgutil.SaveResult, len(allNames))
+	var (
+		pushed name.Reference
+		idx    int
+	)
+	for ; idx < len(allNames) && pushed == nil; idx++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		results[idx] = saved
+		results[idx].Name = allNames[idx]
+		if pushed, err = i.doSave(ctx, allNames[idx], &results[idx]); err != nil {
+			results[idx] = imgutil.SaveResult{Name: allNames[idx], Err: err}
+		}
+	}
+
+	var g errgroup.Group
+	for ; idx < len(allNames); idx++ {
+		idx := idx
+		g.Go(func() error {
+			results[idx] = saved
+			results[idx].Name = allNames[idx]
+			if err := i.doTag(ctx, allNames[idx], pushed, &results[idx]); err != nil {
+				results[idx] = imgutil.SaveResult{Name: allNames[idx], Err: err}
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, imgutil.SaveErrorFrom(results)
+}
+
+// prepareForSave normalizes the creation time, history and layers of the image before it is written out.
+func (i *Image) prepareForSave() error {
 	var err error
-	allNames := append([]string{name}, additionalNames...)
 
 	// create time
 	if i.image, err = mutate.CreatedAt(i.image, v1.Time{Time: i.createdAt}); err != nil {
@@ -70,43 +156,129 @@ func (i *Image) SaveAs(name string, additionalNames ...string) error {
 			return fmt.Errorf("adding empty layer: %w", err)
 		}
 	}
+	return nil
+}
 
-	// save
-	var diagnostics []imgutil.SaveDiagnostic
-	for _, n := range allNames {
-		if err := i.doSave(n); err != nil {
-			diagnostics = append(diagnostics, imgutil.SaveDiagnostic{ImageName: n, Cause: err})
-		}
+// doSave pushes the image as imageName, and reports the layers that were mounted and uploaded on result.
+// Layers of the base and previous images are mounted from their repositories when they are on the same registry.
+// It returns the reference the image was pushed to.
+func (i *Image) doSave(ctx context.Context, imageName string, result *imgutil.SaveResult) (name.Reference, error) {
+	ref, auth, transport, err := i.endpoint(imageName)
+	if err != nil {
+		return nil, err
 	}
-	if len(diagnostics) > 0 {
-		return imgutil.SaveError{Errors: diagnostics}
+	if err = i.write(ctx, ref, auth, transport, i.mountSources, result); err != nil {
+		return nil, err
 	}
-
-	return nil
+	return ref, nil
 }
 
-func (i *Image) doSave(imageName string) error {
-	reg := getRegistry(i.repoName, i.registrySettings)
-	ref, auth, err := referenceForRepoName(i.keychain, imageName, reg.insecure)
+// doTag saves the image as imageName once its content has been pushed to the pushed reference.
+// Within the same repository only the manifest is put, and within the same registry the content is mounted
+// from the repository it was pushed to; otherwise the content is pushed again.
+func (i *Image) doTag(ctx context.Context, imageName string, pushed name.Reference, result *imgutil.SaveResult) error {
+	ref, auth, transport, err := i.endpoint(imageName)
 	if err != nil {
 		return err
 	}
 
-	return remote.Write(ref, i.image,
-		remote.WithAuth(auth),
-		remote.WithTransport(getTransport(reg.insecure)),
-	)
+	switch {
+	case ref.Context().Name() == pushed.Context().Name():
+		raw, err := i.image.RawManifest()
+		if err != nil {
+			return err
+		}
+		mediaType, err := i.image.MediaType()
+		if err != nil {
+			return err
+		}
+		return registryError(i.retryPolicy.do(ctx, transport, func(rt http.RoundTripper) error {
+			return remote.Put(ref, &manifestOnly{raw: raw, mediaType: mediaType}, append(i.retryPolicy.remoteOptions(),
+				remote.WithAuth(auth),
+				remote.WithTransport(rt),
+				remote.WithContext(ctx),
+			)...)
+		}))
+	case ref.Context().RegistryStr() == pushed.Context().RegistryStr():
+		sources, err := blobSources(i.image, pushed.Context())
+		if err != nil {
+			return err
+		}
+		return i.write(ctx, ref, auth, transport, sources, result)
+	default:
+		_, err = i.doSave(ctx, imageName, result)
+		return err
+	}
 }
 
-func getTransport(insecure bool) http.RoundTripper {
-	// #nosec G402
-	if insecure {
-		return &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
+// endpoint returns the reference, authenticator and transport to use to access the image named imageName.
+func (i *Image) endpoint(imageName string) (name.Reference, authn.Authenticator, http.RoundTripper, error) {
+	reg := getRegistry(imageName, i.registrySettings)
+	ref, auth, err := referenceForRepoName(i.keychain, imageName, reg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	transport, err := reg.transport()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return ref, auth, transport, nil
+}
+
+// write pushes the image to ref, mounting the blobs from the given sources where possible,
+// and reports the layers that were mounted and uploaded on result.
+func (i *Image) write(ctx context.Context, ref name.Reference, auth authn.Authenticator, transport http.RoundTripper, sources map[v1.Hash]name.Repository, result *imgutil.SaveResult) error {
+	recorder, err := newLayerRecorder(transport, i.image, i.progress)
+	if err != nil {
+		return err
+	}
+
+	image := newMountableImage(imgutil.ProgressReportingImage(i.image, imgutil.PhaseUploading, i.progress), ref.Context(), sources)
+	if err = i.retryPolicy.do(ctx, recorder, func(rt http.RoundTripper) error {
+		options := append(i.retryPolicy.remoteOptions(),
+			remote.WithAuth(auth),
+			remote.WithTransport(rt),
+			remote.WithContext(ctx),
+		)
+		if i.progress != nil {
+			updates := make(chan v1.Update, 100)
+			defer forwardProgress(updates, i.progress)()
+			options = append(options, remote.WithProgress(updates))
 		}
+		return remote.Write(ref, image, options...)
+	}); err != nil {
+		return registryError(err)
 	}
+	return recorder.report(i.image, result)
+}
+
+// forwardProgress reports the updates sent by remote.Write to handler, as the progress of the push as a whole.
+// remote.Write closes updates when it returns; the returned function waits until every update has been reported.
+func forwardProgress(updates <-chan v1.Update, handler imgutil.ProgressHandler) (wait func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for update := range updates {
+			if update.Error != nil {
+				continue
+			}
+			handler(imgutil.Progress{Complete: update.Complete, Total: update.Total, Phase: imgutil.PhaseUploading})
+		}
+	}()
+	return func() { <-done }
+}
+
+// manifestOnly is the manifest of an image without the blobs it references,
+// so that writing it puts the manifest without checking for or pushing the blobs.
+type manifestOnly struct {
+	raw       []byte
+	mediaType types.MediaType
+}
+
+func (m *manifestOnly) RawManifest() ([]byte, error) {
+	return m.raw, nil
+}
 
-	return http.DefaultTransport
+func (m *manifestOnly) MediaType() (types.MediaType, error) {
+	return m.mediaType, nil
 }
@@ -1,9 +1,44 @@
 package remote
 
 import (
-	"errors"
+	"fmt"
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/buildpacks/imgutil"
 )
 
+// SaveFile saves the image as a docker archive and provides the filesystem location.
+// The archive is tagged with the image name, and layers are streamed from the registry into the archive.
 func (i *Image) SaveFile() (string, error) {
-	return "", errors.New("not yet implemented")
+	if err := i.prepareForSave(); err != nil {
+		return "", err
+	}
+	return i.saveFile()
+}
+
+// saveFile saves the image as is as a docker archive tagged with the image name.
+func (i *Image) saveFile() (string, error) {
+	tag, err := name.NewTag(i.repoName, name.WeakValidation)
+	if err != nil {
+		return "", fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	f, err := os.CreateTemp("", "imgutil.remote.image.export.*.tar")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		f.Close()
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err = tarball.Write(tag, imgutil.ProgressReportingImage(i.image, imgutil.PhaseWriting, i.progress), f); err != nil {
+		return "", fmt.Errorf("writing image %q to file: %w", i.repoName, err)
+	}
+	return f.Name(), nil
 }
@@ -0,0 +1,176 @@
+package remote
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/blobcache"
+)
+
+// Store provides methods for interacting with registries
+// in order to save, delete, and report the presence of images.
+// Images are saved with the registry settings and the retry policy of the store, mounting the layers of images read
+// through the store from other repositories of the same registry.
+type Store struct {
+	// required
+	keychain authn.Keychain
+	// optional
+	registrySettings map[string]RegistryConfig
+	retryPolicy      RetryPolicy
+	progress         imgutil.ProgressHandler
+	blobCache        *blobcache.Cache
+	mountSources     map[v1.Hash]name.Repository // layer digest -> repository the layer can be mounted from
+}
+
+var _ imgutil.ContextImageStore = &Store{}
+
+// NewStore returns a store that accesses registries with the given keychain.
+// The registry settings, retry policy, progress handler and blob cache are set from ops; other options are ignored.
+func NewStore(keychain authn.Keychain, ops ...ImageOption) (*Store, error) {
+	imageOpts := &options{}
+	for _, op := range ops {
+		if err := op(imageOpts); err != nil {
+			return nil, err
+		}
+	}
+	return newStore(keychain, imageOpts), nil
+}
+
+func newStore(keychain authn.Keychain, imageOpts *options) *Store {
+	store := &Store{
+		keychain:         keychain,
+		registrySettings: imageOpts.registrySettings,
+		progress:         imageOpts.progress,
+		blobCache:        imageOpts.blobCache,
+		mountSources:     make(map[v1.Hash]name.Repository),
+	}
+	if imageOpts.retryPolicy != nil {
+		store.retryPolicy = *imageOpts.retryPolicy
+	}
+	return store
+}
+
+// images
+
+func (s *Store) Contains(identifier string) bool {
+	return s.ContainsContext(context.Background(), identifier)
+}
+
+func (s *Store) ContainsContext(ctx context.Context, identifier string) bool {
+	_, err := s.image(identifier, nil).found(ctx)
+	return err == nil
+}
+
+func (s *Store) Delete(identifier string) error {
+	return s.DeleteContext(context.Background(), identifier)
+}
+
+func (s *Store) DeleteContext(ctx context.Context, identifier string) error {
+	reg := getRegistry(identifier, s.registrySettings)
+	ref, auth, err := referenceForRepoName(s.keychain, identifier, reg)
+	if err != nil {
+		return err
+	}
+	transport, err := reg.transport()
+	if err != nil {
+		return err
+	}
+	return registryError(s.retryPolicy.do(ctx, transport, func(rt http.RoundTripper) error {
+		return remote.Delete(ref, append(s.retryPolicy.remoteOptions(),
+			remote.WithAuth(auth),
+			remote.WithTransport(rt),
+			remote.WithContext(ctx),
+		)...)
+	}))
+}
+
+// Save pushes the image with each name, and returns the identifier of the image in the repository of the first name,
+// i.e. the repository followed by "@" and the digest of the image.
+func (s *Store) Save(image imgutil.IdentifiableV1Image, withName string, withAdditionalNames ...string) (string, error) {
+	results, err := s.SaveContext(context.Background(), image, withName, withAdditionalNames...)
+	if err != nil {
+		return "", err
+	}
+	ref, err := name.ParseReference(withName, name.WeakValidation)
+	if err != nil {
+		return "", err
+	}
+	return ref.Context().Digest(results[0].Digest.String()).String(), nil
+}
+
+// SaveContext pushes the image with each name, returning the outcome for each name.
+// The content is pushed once, and then only the manifest is put, or the content mounted, for the other names.
+func (s *Store) SaveContext(ctx context.Context, image imgutil.IdentifiableV1Image, withName string, withAdditionalNames ...string) ([]imgutil.SaveResult, error) {
+	return s.image(withName, image).push(ctx, append([]string{withName}, withAdditionalNames...))
+}
+
+// SaveFile saves the image as a docker archive tagged with the given name, and provides its filesystem location.
+func (s *Store) SaveFile(image imgutil.IdentifiableV1Image, withName string) (string, error) {
+	return s.image(withName, image).saveFile()
+}
+
+// SaveFileContext is the same as SaveFile, as the archive is written from the image content.
+func (s *Store) SaveFileContext(_ context.Context, image imgutil.IdentifiableV1Image, withName string) (string, error) {
+	return s.SaveFile(image, withName)
+}
+
+// layers
+
+// DownloadLayersFor does nothing, as the layers of images in a registry are fetched when they are read.
+func (s *Store) DownloadLayersFor(_ string) error {
+	return nil
+}
+
+// DownloadLayersForContext does nothing, as the layers of images in a registry are fetched when they are read.
+func (s *Store) DownloadLayersForContext(_ context.Context, _ string) error {
+	return nil
+}
+
+// Layers returns no layers, as the store doesn't keep layers on disk.
+func (s *Store) Layers() []v1.Layer {
+	return nil
+}
+
+// helpers
+
+// readImage returns the image with the given name, read from the first of its mirrors that serves it,
+// and the name it was read by. Its layers are mountable from that repository when it is saved to the same registry.
+func (s *Store) readImage(repoName string, platform imgutil.Platform, mirrors map[string][]string, strict bool) (v1.Image, string, error) {
+	sources := readSources(repoName, mirrors, func(repoName string) RegistryConfig {
+		return getRegistry(repoName, s.registrySettings)
+	})
+	image, source, err := newV1Image(context.Background(), s.keychain, sources, platform, s.retryPolicy, strict)
+	if err != nil {
+		return nil, "", err
+	}
+	if s.blobCache != nil {
+		image = s.blobCache.Image(image)
+	}
+	if source != "" {
+		if err := s.image(repoName, nil).recordMountSources(image, repoName); err != nil {
+			return nil, "", err
+		}
+	}
+	return image, source, nil
+}
+
+// image returns an Image with the given name and content that accesses the registry as configured for the store,
+// and shares the mount sources of the store.
+func (s *Store) image(repoName string, image v1.Image) *Image {
+	return &Image{
+		keychain:         s.keychain,
+		repoName:         repoName,
+		image:            image,
+		progress:         s.progress,
+		registrySettings: s.registrySettings,
+		retryPolicy:      s.retryPolicy,
+		mountSources:     s.mountSources,
+		blobCache:        s.blobCache,
+	}
+}
@@ -0,0 +1,51 @@
+package remote_test
+
+import (
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/imgutil/remote"
+	h "github.com/buildpacks/imgutil/testhelpers"
+)
+
+func TestStore(t *testing.T) {
+	spec.Run(t, "Store", testStore, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testStore(t *testing.T, when spec.G, it spec.S) {
+	when("#Save", func() {
+		it("returns an identifier that the store resolves", func() {
+			server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", log.Lshortfile))))
+			defer server.Close()
+			host := strings.TrimPrefix(server.URL, "http://")
+			opts := []remote.ImageOption{remote.WithRegistryConfig(host, remote.RegistryConfig{PlainHTTP: true})}
+			store, err := remote.NewStore(authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			image, err := remote.NewCNBImage(host+"/some-image", authn.DefaultKeychain, opts...)
+			h.AssertNil(t, err)
+			layerPath, err := h.CreateSingleFileLayerTar("/some-file.txt", "some-content", "linux")
+			h.AssertNil(t, err)
+			defer os.Remove(layerPath)
+			h.AssertNil(t, image.AddLayer(layerPath))
+
+			identifier, err := store.Save(image, host+"/some-image:some-tag")
+			h.AssertNil(t, err)
+			expected, err := image.Identifier()
+			h.AssertNil(t, err)
+			h.AssertEq(t, identifier, expected.String())
+
+			h.AssertEq(t, store.Contains(identifier), true)
+			h.AssertNil(t, store.Delete(identifier))
+			h.AssertEq(t, store.Contains(identifier), false)
+		})
+	})
+}
@@ -0,0 +1,182 @@
+// Package sign signs images with local keys and verifies their signatures.
+//
+// Signatures are compatible with cosign: the signed payload is a simple signing payload naming the image by
+// repository and digest, and it is stored, with its signature, as an artifact attached to the image in the registry
+// or OCI layout the image was saved to.
+package sign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/buildpacks/imgutil"
+)
+
+const (
+	// ArtifactType is the artifact type of signatures.
+	ArtifactType = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	// PayloadMediaType is the media type of the signed payload.
+	PayloadMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// SignatureAnnotation is the annotation of the payload that holds its base64 encoded signature.
+	SignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+	payloadType = "cosign container image signature"
+)
+
+// ErrNoValidSignature is returned by Verify when the image has no signature that the public key verifies.
+var ErrNoValidSignature = errors.New("no valid signature found")
+
+// Signable is an image that artifacts can be attached to, such as remote and layout images.
+type Signable interface {
+	imgutil.Image
+	Attach(artifact imgutil.Artifact) (v1.Descriptor, error)
+	Referrers(artifactType string) ([]v1.Descriptor, error)
+	ReadArtifact(desc v1.Descriptor) (imgutil.Artifact, error)
+}
+
+// payload is the simple signing payload of an image signature.
+type payload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]interface{} `json:"optional"`
+}
+
+// Sign signs the image with key, which must be an ECDSA or ed25519 private key, and attaches the signature to it.
+// The image must be saved first, as the signature is for the digest it was saved with.
+func Sign(image Signable, key crypto.Signer) (v1.Descriptor, error) {
+	digest, err := digestOf(image)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	var p payload
+	p.Critical.Identity.DockerReference = identity(image)
+	p.Critical.Image.DockerManifestDigest = digest.String()
+	p.Critical.Type = payloadType
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+
+	signature, err := signPayload(key, raw)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	return image.Attach(imgutil.Artifact{
+		ArtifactType: ArtifactType,
+		Blobs: []imgutil.ArtifactBlob{{
+			MediaType:   PayloadMediaType,
+			Data:        raw,
+			Annotations: map[string]string{SignatureAnnotation: base64.StdEncoding.EncodeToString(signature)},
+		}},
+	})
+}
+
+// Verify checks that the image has a signature made with the private key of publicKey, which must be an ECDSA or
+// ed25519 public key, for the digest the image was saved with.
+// Signatures that cannot be read or parsed are skipped; it returns ErrNoValidSignature if none of the others is valid.
+func Verify(image Signable, publicKey crypto.PublicKey) error {
+	digest, err := digestOf(image)
+	if err != nil {
+		return err
+	}
+	referrers, err := image.Referrers(ArtifactType)
+	if err != nil {
+		return fmt.Errorf("listing signatures of image %q: %w", image.Name(), err)
+	}
+	for _, referrer := range referrers {
+		artifact, err := image.ReadArtifact(referrer)
+		if err != nil {
+			continue
+		}
+		for _, blob := range artifact.Blobs {
+			if blob.MediaType != PayloadMediaType {
+				continue
+			}
+			signature, err := base64.StdEncoding.DecodeString(blob.Annotations[SignatureAnnotation])
+			if err != nil {
+				continue
+			}
+			ok, err := verifyPayload(publicKey, blob.Data, signature)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			var p payload
+			if err := json.Unmarshal(blob.Data, &p); err != nil {
+				continue
+			}
+			if p.Critical.Type == payloadType && p.Critical.Image.DockerManifestDigest == digest.String() {
+				return nil
+			}
+		}
+	}
+	return ErrNoValidSignature
+}
+
+func signPayload(key crypto.Signer, payload []byte) ([]byte, error) {
+	switch key.Public().(type) {
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256(payload)
+		return key.Sign(rand.Reader, sum[:], crypto.SHA256)
+	case ed25519.PublicKey:
+		return key.Sign(rand.Reader, payload, crypto.Hash(0))
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key.Public())
+	}
+}
+
+func verifyPayload(publicKey crypto.PublicKey, payload, signature []byte) (bool, error) {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256(payload)
+		return ecdsa.VerifyASN1(key, sum[:], signature), nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, payload, signature), nil
+	default:
+		return false, fmt.Errorf("unsupported key type %T", publicKey)
+	}
+}
+
+// digestOf returns the digest the image was saved with, which the identifiers of saved images end with.
+func digestOf(image imgutil.Image) (v1.Hash, error) {
+	identifier, err := image.Identifier()
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("getting identifier of image %q: %w", image.Name(), err)
+	}
+	id := identifier.String()
+	digest, err := v1.NewHash(id[strings.LastIndex(id, "@")+1:])
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("getting digest of image %q: %w", image.Name(), err)
+	}
+	return digest, nil
+}
+
+// identity returns the repository of images in a registry, and the name of other images, e.g. the path of a layout.
+func identity(image imgutil.Image) string {
+	if image.Kind() == "remote" {
+		if ref, err := name.ParseReference(image.Name(), name.WeakValidation); err == nil {
+			return ref.Context().Name()
+		}
+	}
+	return image.Name()
+}
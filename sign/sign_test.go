@@ -0,0 +1,207 @@
+package sign_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/imgutil"
+	"github.com/buildpacks/imgutil/layout"
+	"github.com/buildpacks/imgutil/remote"
+	"github.com/buildpacks/imgutil/sign"
+	h "github.com/buildpacks/imgutil/testhelpers"
+)
+
+var dockerRegistry *h.DockerRegistry
+
+func TestSign(t *testing.T) {
+	dockerConfigDir, err := os.MkdirTemp("", "test.docker.config.dir")
+	h.AssertNil(t, err)
+	defer os.RemoveAll(dockerConfigDir)
+
+	dockerRegistry = h.NewDockerRegistry(h.WithAuth(dockerConfigDir))
+	dockerRegistry.Start(t)
+	defer dockerRegistry.Stop(t)
+
+	os.Setenv("DOCKER_CONFIG", dockerRegistry.DockerDirectory)
+	defer os.Unsetenv("DOCKER_CONFIG")
+
+	spec.Run(t, "Sign", testSign, spec.Sequential(), spec.Report(report.Terminal{}))
+}
+
+func testSign(t *testing.T, when spec.G, it spec.S) {
+	var (
+		ecdsaKey   *ecdsa.PrivateKey
+		ed25519Key ed25519.PrivateKey
+	)
+
+	it.Before(func() {
+		var err error
+		ecdsaKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		h.AssertNil(t, err)
+		_, ed25519Key, err = ed25519.GenerateKey(rand.Reader)
+		h.AssertNil(t, err)
+	})
+
+	signAndVerify := func(newImage func() sign.Signable) {
+		it("verifies the signature made with an ECDSA key", func() {
+			image := newImage()
+			_, err := sign.Sign(image, ecdsaKey)
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, sign.Verify(image, ecdsaKey.Public()))
+		})
+
+		it("verifies the signature made with an ed25519 key", func() {
+			image := newImage()
+			_, err := sign.Sign(image, ed25519Key)
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, sign.Verify(image, ed25519Key.Public()))
+		})
+
+		it("stores a simple signing payload for the digest of the image", func() {
+			image := newImage()
+			desc, err := sign.Sign(image, ecdsaKey)
+			h.AssertNil(t, err)
+			h.AssertEq(t, desc.ArtifactType, sign.ArtifactType)
+
+			artifact, err := image.ReadArtifact(desc)
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(artifact.Blobs), 1)
+			h.AssertEq(t, string(artifact.Blobs[0].MediaType), sign.PayloadMediaType)
+			h.AssertNotEq(t, artifact.Blobs[0].Annotations[sign.SignatureAnnotation], "")
+
+			var payload struct {
+				Critical struct {
+					Image struct {
+						DockerManifestDigest string `json:"docker-manifest-digest"`
+					} `json:"image"`
+					Type string `json:"type"`
+				} `json:"critical"`
+			}
+			h.AssertNil(t, json.Unmarshal(artifact.Blobs[0].Data, &payload))
+			h.AssertEq(t, payload.Critical.Type, "cosign container image signature")
+			identifier, err := image.Identifier()
+			h.AssertNil(t, err)
+			h.AssertMatch(t, identifier.String(), regexp.MustCompile("@"+regexp.QuoteMeta(payload.Critical.Image.DockerManifestDigest)+"$"))
+		})
+
+		it("fails to verify the image with another key", func() {
+			image := newImage()
+			_, err := sign.Sign(image, ecdsaKey)
+			h.AssertNil(t, err)
+
+			otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			h.AssertNil(t, err)
+			h.AssertError(t, sign.Verify(image, otherKey.Public()), sign.ErrNoValidSignature.Error())
+			h.AssertError(t, sign.Verify(image, ed25519Key.Public()), sign.ErrNoValidSignature.Error())
+		})
+
+		it("fails to verify an unsigned image", func() {
+			image := newImage()
+			h.AssertError(t, sign.Verify(image, ecdsaKey.Public()), sign.ErrNoValidSignature.Error())
+		})
+	}
+
+	when("the image is in a registry", func() {
+		signAndVerify(func() sign.Signable {
+			image, err := remote.NewImage(dockerRegistry.RepoName("sign-test-"+h.RandString(10)), authn.DefaultKeychain)
+			h.AssertNil(t, err)
+			h.AssertNil(t, image.Save())
+			return image
+		})
+
+		it("does not verify the image once it is saved with other content", func() {
+			repoName := dockerRegistry.RepoName("sign-test-" + h.RandString(10))
+			image, err := remote.NewImage(repoName, authn.DefaultKeychain)
+			h.AssertNil(t, err)
+			h.AssertNil(t, image.Save())
+			_, err = sign.Sign(image, ecdsaKey)
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, image.SetLabel("some-label", "some-value"))
+			h.AssertNil(t, image.Save())
+			h.AssertError(t, sign.Verify(image, ecdsaKey.Public()), sign.ErrNoValidSignature.Error())
+		})
+	})
+
+	when("the image is in an OCI layout", func() {
+		signAndVerify(func() sign.Signable {
+			image, err := layout.NewImage(filepath.Join(t.TempDir(), "some-image"))
+			h.AssertNil(t, err)
+			h.AssertNil(t, image.Save())
+			return image
+		})
+
+		it("skips signatures that cannot be read", func() {
+			imagePath := filepath.Join(t.TempDir(), "some-image")
+			image, err := layout.NewImage(imagePath)
+			h.AssertNil(t, err)
+			h.AssertNil(t, image.Save())
+			data := []byte("some-unreadable-signature")
+			_, err = image.Attach(imgutil.Artifact{
+				ArtifactType: sign.ArtifactType,
+				Blobs:        []imgutil.ArtifactBlob{{MediaType: sign.PayloadMediaType, Data: data}},
+			})
+			h.AssertNil(t, err)
+			digest, _, err := v1.SHA256(bytes.NewReader(data))
+			h.AssertNil(t, err)
+			h.AssertNil(t, os.Remove(filepath.Join(imagePath, "blobs", digest.Algorithm, digest.Hex)))
+			_, err = sign.Sign(image, ecdsaKey)
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, sign.Verify(image, ecdsaKey.Public()))
+		})
+	})
+
+	when("#ParsePrivateKey", func() {
+		it("parses PKCS #8 and SEC 1 keys", func() {
+			for _, key := range []crypto.Signer{ecdsaKey, ed25519Key} {
+				der, err := x509.MarshalPKCS8PrivateKey(key)
+				h.AssertNil(t, err)
+				parsed, err := sign.ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+				h.AssertNil(t, err)
+				h.AssertEq(t, parsed, key)
+			}
+
+			der, err := x509.MarshalECPrivateKey(ecdsaKey)
+			h.AssertNil(t, err)
+			parsed, err := sign.ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+			h.AssertNil(t, err)
+			h.AssertEq(t, parsed, crypto.Signer(ecdsaKey))
+		})
+
+		it("returns an error for data without a key", func() {
+			_, err := sign.ParsePrivateKey([]byte("not a key"))
+			h.AssertError(t, err, "no PEM encoded private key found")
+		})
+	})
+
+	when("#ParsePublicKey", func() {
+		it("parses PKIX keys", func() {
+			for _, key := range []crypto.PublicKey{ecdsaKey.Public(), ed25519Key.Public()} {
+				der, err := x509.MarshalPKIXPublicKey(key)
+				h.AssertNil(t, err)
+				parsed, err := sign.ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+				h.AssertNil(t, err)
+				h.AssertEq(t, parsed, key)
+			}
+		})
+	})
+}